@@ -2,11 +2,15 @@ package ast
 
 import (
 	"bytes"
+
+	"github.com/rumpl/monkey-lang/token"
 )
 
 type Node interface {
 	TokenLiteral() string
 	String() string
+	// Pos returns the position in the source of the token the node starts at
+	Pos() token.Position
 }
 
 type Statement interface {
@@ -29,6 +33,14 @@ func (p *Program) TokenLiteral() string {
 	return ""
 }
 
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+
+	return token.Position{}
+}
+
 func (p *Program) String() string {
 	var out bytes.Buffer
 
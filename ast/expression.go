@@ -2,6 +2,7 @@ package ast
 
 import (
 	"bytes"
+	"math/big"
 	"strconv"
 	"strings"
 
@@ -18,6 +19,10 @@ func (p *PrefixExpression) TokenLiteral() string {
 	return p.Token.Literal
 }
 
+func (p *PrefixExpression) Pos() token.Position {
+	return p.Token.Pos()
+}
+
 func (p *PrefixExpression) String() string {
 	var out bytes.Buffer
 
@@ -29,6 +34,26 @@ func (p *PrefixExpression) String() string {
 	return out.String()
 }
 
+// PostfixExpression is an operator following its operand, "r?" returns the
+// error of the result r from the enclosing function
+type PostfixExpression struct {
+	Token    token.Token
+	Left     Expression
+	Operator string
+}
+
+func (pe *PostfixExpression) TokenLiteral() string {
+	return pe.Token.Literal
+}
+
+func (pe *PostfixExpression) Pos() token.Position {
+	return pe.Token.Pos()
+}
+
+func (pe *PostfixExpression) String() string {
+	return "(" + pe.Left.String() + pe.Operator + ")"
+}
+
 type InfixExpression struct {
 	Token    token.Token
 	Left     Expression
@@ -40,6 +65,10 @@ func (i *InfixExpression) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (i *InfixExpression) Pos() token.Position {
+	return i.Token.Pos()
+}
+
 func (i *InfixExpression) String() string {
 	var out bytes.Buffer
 
@@ -55,16 +84,111 @@ func (i *InfixExpression) String() string {
 type Identifier struct {
 	Token token.Token // the token.IDENT token
 	Value string
+	// Binding is the variable the identifier refers to, it is set by the
+	// resolver and nil until then
+	Binding *Binding
+}
+
+// BindingKind tells where the variable an identifier refers to is defined
+type BindingKind int
+
+const (
+	// Unresolved identifiers don't refer to any variable
+	Unresolved BindingKind = iota
+	// Global variables are defined at the top level of the program
+	Global
+	// Local variables are defined in the function the identifier is in
+	Local
+	// Free variables are defined in an enclosing function
+	Free
+	// Builtin identifiers refer to a function of the interpreter
+	Builtin
+)
+
+func (k BindingKind) String() string {
+	switch k {
+	case Global:
+		return "global"
+	case Local:
+		return "local"
+	case Free:
+		return "free"
+	case Builtin:
+		return "builtin"
+	}
+	return "unresolved"
+}
+
+// Binding is the variable an identifier refers to
+type Binding struct {
+	Kind BindingKind
+	// Depth is the number of functions between the identifier and the
+	// definition of the variable
+	Depth int
+	// Pos is where the variable is defined
+	Pos token.Position
 }
 
 func (i *Identifier) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (i *Identifier) Pos() token.Position {
+	return i.Token.Pos()
+}
+
 func (i *Identifier) String() string {
 	return i.Value
 }
 
+func (i *Identifier) assignable() {}
+
+// Declaration is what a let statement or a parameter of a function declares,
+// a variable or the variables of a pattern destructuring the value
+type Declaration struct {
+	// Pattern is the *Identifier of the variable, or the array or hash
+	// pattern destructuring the value
+	Pattern Pattern
+	// Type is the annotation of the variable, nil when there is none
+	Type TypeExpression
+	// Default is the value of a parameter the call doesn't pass, nil when
+	// the parameter is required
+	Default Expression
+	// Rest is set for the ...name parameter collecting the arguments left
+	Rest bool
+}
+
+func (d *Declaration) TokenLiteral() string {
+	return d.Pattern.TokenLiteral()
+}
+
+func (d *Declaration) Pos() token.Position {
+	return d.Pattern.Pos()
+}
+
+// String writes the declaration as it is written in the source, with its
+// type annotation and default
+func (d *Declaration) String() string {
+	decl := d.Pattern.String()
+	if d.Rest {
+		decl = "..." + decl
+	}
+	if d.Type != nil {
+		decl += ": " + d.Type.String()
+	}
+	if d.Default != nil {
+		decl += " = " + d.Default.String()
+	}
+	return decl
+}
+
+// Variable returns the identifier of the variable declared, nil when the
+// value is destructured
+func (d *Declaration) Variable() *Identifier {
+	id, _ := d.Pattern.(*Identifier)
+	return id
+}
+
 type IntegerLiteral struct {
 	Token token.Token
 	Value int64
@@ -74,10 +198,182 @@ func (i *IntegerLiteral) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (i *IntegerLiteral) Pos() token.Position {
+	return i.Token.Pos()
+}
+
+// String writes the integer the way it was written in the source, in the
+// same base and with the same separators
 func (i *IntegerLiteral) String() string {
+	if i.Token.Type == token.INT && i.Token.Literal != "" {
+		return i.Token.Literal
+	}
 	return strconv.Itoa(int(i.Value))
 }
 
+// BigIntegerLiteral is an integer literal too large for an int64
+type BigIntegerLiteral struct {
+	Token token.Token
+	Value *big.Int
+}
+
+func (b *BigIntegerLiteral) TokenLiteral() string {
+	return b.Token.Literal
+}
+
+func (b *BigIntegerLiteral) Pos() token.Position {
+	return b.Token.Pos()
+}
+
+func (b *BigIntegerLiteral) String() string {
+	if b.Token.Type == token.INT && b.Token.Literal != "" {
+		return b.Token.Literal
+	}
+	return b.Value.String()
+}
+
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (f *FloatLiteral) TokenLiteral() string {
+	return f.Token.Literal
+}
+
+func (f *FloatLiteral) Pos() token.Position {
+	return f.Token.Pos()
+}
+
+func (f *FloatLiteral) String() string {
+	return FormatFloat(f.Value)
+}
+
+// FormatFloat formats f so that it reads back as a float, 3 is written 3.0
+func FormatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
+type StringLiteral struct {
+	Token token.Token
+	Value string
+}
+
+func (sl *StringLiteral) TokenLiteral() string {
+	return sl.Token.Literal
+}
+
+func (sl *StringLiteral) Pos() token.Position {
+	return sl.Token.Pos()
+}
+
+func (sl *StringLiteral) String() string {
+	return strconv.Quote(sl.Value)
+}
+
+type ArrayLiteral struct {
+	Token    token.Token // the [ token
+	Elements []Expression
+}
+
+func (al *ArrayLiteral) TokenLiteral() string {
+	return al.Token.Literal
+}
+
+func (al *ArrayLiteral) Pos() token.Position {
+	return al.Token.Pos()
+}
+
+func (al *ArrayLiteral) String() string {
+	elements := []string{}
+	for _, el := range al.Elements {
+		elements = append(elements, el.String())
+	}
+
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// HashPair is a key and its value in a hash literal
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
+type HashLiteral struct {
+	Token token.Token // the { token
+	Pairs []HashPair  // in the order they are written
+}
+
+func (hl *HashLiteral) TokenLiteral() string {
+	return hl.Token.Literal
+}
+
+func (hl *HashLiteral) Pos() token.Position {
+	return hl.Token.Pos()
+}
+
+func (hl *HashLiteral) String() string {
+	pairs := []string{}
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+type IndexExpression struct {
+	Token token.Token // the [ token
+	Left  Expression
+	Index Expression
+}
+
+func (ie *IndexExpression) TokenLiteral() string {
+	return ie.Token.Literal
+}
+
+func (ie *IndexExpression) Pos() token.Position {
+	return ie.Token.Pos()
+}
+
+func (ie *IndexExpression) String() string {
+	return "(" + ie.Left.String() + "[" + ie.Index.String() + "])"
+}
+
+func (ie *IndexExpression) assignable() {}
+
+// FieldExpression reads the field Field of Left, h.name is h["name"] for a
+// hash
+type FieldExpression struct {
+	Token token.Token // the . token
+	Left  Expression
+	Field *Identifier
+}
+
+func (fe *FieldExpression) TokenLiteral() string {
+	return fe.Token.Literal
+}
+
+func (fe *FieldExpression) Pos() token.Position {
+	return fe.Token.Pos()
+}
+
+func (fe *FieldExpression) String() string {
+	return "(" + fe.Left.String() + "." + fe.Field.Value + ")"
+}
+
+func (fe *FieldExpression) assignable() {}
+
+// Assignable is an expression that can be assigned to: a variable, an
+// element of an array or a hash, or a field
+type Assignable interface {
+	Expression
+	assignable()
+}
+
 type Boolean struct {
 	Token token.Token
 	Value bool
@@ -87,6 +383,10 @@ func (b *Boolean) TokenLiteral() string {
 	return b.Token.Literal
 }
 
+func (b *Boolean) Pos() token.Position {
+	return b.Token.Pos()
+}
+
 func (b *Boolean) String() string {
 	return b.Token.Literal
 }
@@ -102,6 +402,10 @@ func (ie *IfExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
 
+func (ie *IfExpression) Pos() token.Position {
+	return ie.Token.Pos()
+}
+
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 
@@ -120,7 +424,8 @@ func (ie *IfExpression) String() string {
 
 type FunctionLiteral struct {
 	Token      token.Token
-	Parameters []*Identifier
+	Parameters []*Declaration
+	ReturnType TypeExpression // nil when the return type is not annotated
 	Body       *BlockStatement
 }
 
@@ -128,6 +433,10 @@ func (fl *FunctionLiteral) TokenLiteral() string {
 	return fl.Token.Literal
 }
 
+func (fl *FunctionLiteral) Pos() token.Position {
+	return fl.Token.Pos()
+}
+
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
@@ -140,11 +449,51 @@ func (fl *FunctionLiteral) String() string {
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
+	if fl.ReturnType != nil {
+		out.WriteString("-> " + fl.ReturnType.String() + " ")
+	}
 	out.WriteString(fl.Body.String())
 
 	return out.String()
 }
 
+// SpreadExpression passes the elements of an array as separate arguments of
+// a call
+type SpreadExpression struct {
+	Token token.Token // the token.ELLIPSIS token
+	Value Expression
+}
+
+func (se *SpreadExpression) TokenLiteral() string {
+	return se.Token.Literal
+}
+
+func (se *SpreadExpression) Pos() token.Position {
+	return se.Token.Pos()
+}
+
+func (se *SpreadExpression) String() string {
+	return "..." + se.Value.String()
+}
+
+// KeywordArgument passes Value to the parameter called Name
+type KeywordArgument struct {
+	Name  *Identifier
+	Value Expression
+}
+
+func (ka *KeywordArgument) TokenLiteral() string {
+	return ka.Name.TokenLiteral()
+}
+
+func (ka *KeywordArgument) Pos() token.Position {
+	return ka.Name.Pos()
+}
+
+func (ka *KeywordArgument) String() string {
+	return ka.Name.String() + ": " + ka.Value.String()
+}
+
 type CallExpression struct {
 	Token     token.Token
 	Function  Expression
@@ -155,6 +504,10 @@ func (ce *CallExpression) TokenLiteral() string {
 	return ce.Token.Literal
 }
 
+func (ce *CallExpression) Pos() token.Position {
+	return ce.Token.Pos()
+}
+
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 
@@ -173,6 +526,7 @@ func (ce *CallExpression) String() string {
 
 type ForExpression struct {
 	Token         token.Token
+	Label         *Identifier // nil when the loop is not labeled
 	Initial       Expression
 	StopCondition Expression
 	Increment     Expression
@@ -183,9 +537,16 @@ func (fe *ForExpression) TokenLiteral() string {
 	return fe.Token.Literal
 }
 
+func (fe *ForExpression) Pos() token.Position {
+	return fe.Token.Pos()
+}
+
 func (fe *ForExpression) String() string {
 	var out bytes.Buffer
 
+	if fe.Label != nil {
+		out.WriteString(fe.Label.String() + ": ")
+	}
 	out.WriteString("for (")
 	out.WriteString(fe.Initial.String())
 	out.WriteString(fe.StopCondition.String())
@@ -197,9 +558,82 @@ func (fe *ForExpression) String() string {
 	return out.String()
 }
 
+// ForInExpression is a loop over the elements of an iterable value, Names
+// holds one name for the element or two for its key and value
+type ForInExpression struct {
+	Token    token.Token
+	Label    *Identifier // nil when the loop is not labeled
+	Names    []*Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fe *ForInExpression) TokenLiteral() string {
+	return fe.Token.Literal
+}
+
+func (fe *ForInExpression) Pos() token.Position {
+	return fe.Token.Pos()
+}
+
+func (fe *ForInExpression) String() string {
+	var out bytes.Buffer
+
+	names := []string{}
+	for _, n := range fe.Names {
+		names = append(names, n.String())
+	}
+
+	if fe.Label != nil {
+		out.WriteString(fe.Label.String() + ": ")
+	}
+	out.WriteString("for (")
+	out.WriteString(strings.Join(names, ", "))
+	out.WriteString(" in ")
+	out.WriteString(fe.Iterable.String())
+	out.WriteString(") {")
+	out.WriteString(fe.Body.String())
+	out.WriteString("}")
+	return out.String()
+}
+
+type WhileExpression struct {
+	Token     token.Token
+	Label     *Identifier // nil when the loop is not labeled
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (we *WhileExpression) TokenLiteral() string {
+	return we.Token.Literal
+}
+
+func (we *WhileExpression) Pos() token.Position {
+	return we.Token.Pos()
+}
+
+func (we *WhileExpression) String() string {
+	var out bytes.Buffer
+
+	if we.Label != nil {
+		out.WriteString(we.Label.String() + ": ")
+	}
+	out.WriteString("while (")
+	out.WriteString(we.Condition.String())
+	out.WriteString(") {")
+	out.WriteString(we.Body.String())
+	out.WriteString("}")
+	return out.String()
+}
+
+// AssignExpression assigns the value of Expression to Left. A compound
+// assignment like x += y is desugared to x = x + y, Operator is "+" then.
+// The left operand of the desugared operation is Left itself when it is an
+// index or a field, its parts are only evaluated once.
 type AssignExpression struct {
 	Token      token.Token
-	Left       *Identifier
+	Left       Assignable
+	Operator   string // the operator of a compound assignment, empty for =
 	Expression Expression
 }
 
@@ -207,13 +641,60 @@ func (ae *AssignExpression) TokenLiteral() string {
 	return ae.Token.Literal
 }
 
+func (ae *AssignExpression) Pos() token.Position {
+	return ae.Token.Pos()
+}
+
 func (ae *AssignExpression) String() string {
 	var out bytes.Buffer
 
-	out.WriteString(ae.Left.Value)
-	out.WriteString(" = ")
-	out.WriteString(ae.Expression.String())
+	out.WriteString(ae.Left.String())
+	if infix, ok := ae.Expression.(*InfixExpression); ok && ae.Operator != "" {
+		out.WriteString(" " + ae.Operator + "= ")
+		out.WriteString(infix.Right.String())
+	} else {
+		out.WriteString(" = ")
+		out.WriteString(ae.Expression.String())
+	}
 	out.WriteString(";")
 
 	return out.String()
 }
+
+// TryExpression runs Body, then Catch with the error bound to Param when Body
+// fails, and Finally in every case. Catch or Finally may be missing but not
+// both.
+type TryExpression struct {
+	Token   token.Token
+	Body    *BlockStatement
+	Param   *Identifier // nil when the catch clause doesn't name the error
+	Catch   *BlockStatement
+	Finally *BlockStatement
+}
+
+func (te *TryExpression) TokenLiteral() string {
+	return te.Token.Literal
+}
+
+func (te *TryExpression) Pos() token.Position {
+	return te.Token.Pos()
+}
+
+func (te *TryExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try {")
+	out.WriteString(te.Body.String())
+	out.WriteString("}")
+	if te.Catch != nil {
+		out.WriteString(" catch ")
+		if te.Param != nil {
+			out.WriteString("(" + te.Param.String() + ") ")
+		}
+		out.WriteString("{" + te.Catch.String() + "}")
+	}
+	if te.Finally != nil {
+		out.WriteString(" finally {" + te.Finally.String() + "}")
+	}
+	return out.String()
+}
@@ -0,0 +1,116 @@
+package ast
+
+// ModifierFunc returns the node replacing the node it is given
+type ModifierFunc func(Node) Node
+
+// Modify walks the tree depth-first and replaces every node with the result
+// of calling modifier on it, children are modified before their parent
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch node := node.(type) {
+	case *Program:
+		for i, statement := range node.Statements {
+			node.Statements[i], _ = Modify(statement, modifier).(Statement)
+		}
+	case *ExpressionStatement:
+		node.Expression, _ = Modify(node.Expression, modifier).(Expression)
+	case *LetStatement:
+		node.Value, _ = Modify(node.Value, modifier).(Expression)
+	case *ReturnStatement:
+		node.ReturnValue, _ = Modify(node.ReturnValue, modifier).(Expression)
+	case *BlockStatement:
+		for i, statement := range node.Statements {
+			node.Statements[i], _ = Modify(statement, modifier).(Statement)
+		}
+	case *FunctionStatement:
+		node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+	case *PrefixExpression:
+		node.Right, _ = Modify(node.Right, modifier).(Expression)
+	case *PostfixExpression:
+		node.Left, _ = Modify(node.Left, modifier).(Expression)
+	case *InfixExpression:
+		node.Left, _ = Modify(node.Left, modifier).(Expression)
+		node.Right, _ = Modify(node.Right, modifier).(Expression)
+	case *IfExpression:
+		node.Condition, _ = Modify(node.Condition, modifier).(Expression)
+		node.Consequence, _ = Modify(node.Consequence, modifier).(*BlockStatement)
+		if node.Alternative != nil {
+			node.Alternative, _ = Modify(node.Alternative, modifier).(*BlockStatement)
+		}
+	case *FunctionLiteral:
+		node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+	case *CallExpression:
+		node.Function, _ = Modify(node.Function, modifier).(Expression)
+		for i, arg := range node.Arguments {
+			node.Arguments[i], _ = Modify(arg, modifier).(Expression)
+		}
+	case *ForExpression:
+		node.Initial, _ = Modify(node.Initial, modifier).(Expression)
+		node.StopCondition, _ = Modify(node.StopCondition, modifier).(Expression)
+		node.Increment, _ = Modify(node.Increment, modifier).(Expression)
+		node.Statements, _ = Modify(node.Statements, modifier).(*BlockStatement)
+	case *ForInExpression:
+		node.Iterable, _ = Modify(node.Iterable, modifier).(Expression)
+		node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+	case *SpreadExpression:
+		node.Value, _ = Modify(node.Value, modifier).(Expression)
+	case *KeywordArgument:
+		node.Value, _ = Modify(node.Value, modifier).(Expression)
+	case *ArrayLiteral:
+		for i, el := range node.Elements {
+			node.Elements[i], _ = Modify(el, modifier).(Expression)
+		}
+	case *HashLiteral:
+		for i, pair := range node.Pairs {
+			node.Pairs[i].Key, _ = Modify(pair.Key, modifier).(Expression)
+			node.Pairs[i].Value, _ = Modify(pair.Value, modifier).(Expression)
+		}
+	case *IndexExpression:
+		node.Left, _ = Modify(node.Left, modifier).(Expression)
+		node.Index, _ = Modify(node.Index, modifier).(Expression)
+	case *WhileExpression:
+		node.Condition, _ = Modify(node.Condition, modifier).(Expression)
+		node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+	case *FieldExpression:
+		node.Left, _ = Modify(node.Left, modifier).(Expression)
+	case *ImplStatement:
+		for _, method := range node.Methods {
+			method.Body, _ = Modify(method.Body, modifier).(*BlockStatement)
+		}
+	case *StructLiteral:
+		for _, field := range node.Fields {
+			field.Value, _ = Modify(field.Value, modifier).(Expression)
+		}
+	case *AssignExpression:
+		// The target itself stays, only its parts can change
+		switch left := node.Left.(type) {
+		case *IndexExpression:
+			left.Left, _ = Modify(left.Left, modifier).(Expression)
+			left.Index, _ = Modify(left.Index, modifier).(Expression)
+		case *FieldExpression:
+			left.Left, _ = Modify(left.Left, modifier).(Expression)
+		}
+		node.Expression, _ = Modify(node.Expression, modifier).(Expression)
+	case *MatchExpression:
+		node.Subject, _ = Modify(node.Subject, modifier).(Expression)
+		for _, arm := range node.Arms {
+			if arm.Guard != nil {
+				arm.Guard, _ = Modify(arm.Guard, modifier).(Expression)
+			}
+			arm.Body, _ = Modify(arm.Body, modifier).(*BlockStatement)
+		}
+	case *ThrowStatement:
+		node.Value, _ = Modify(node.Value, modifier).(Expression)
+	case *TryExpression:
+		node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+		if node.Catch != nil {
+			node.Catch, _ = Modify(node.Catch, modifier).(*BlockStatement)
+		}
+		if node.Finally != nil {
+			node.Finally, _ = Modify(node.Finally, modifier).(*BlockStatement)
+		}
+	case nil:
+		return nil
+	}
+
+	return modifier(node)
+}
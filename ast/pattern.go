@@ -0,0 +1,170 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/rumpl/monkey-lang/token"
+)
+
+// Pattern is the left side of an arm of a match expression. Besides the
+// patterns below, an identifier matches any value and binds it, and an
+// integer, float, string or boolean literal, possibly negated, matches the
+// values equal to it.
+type Pattern interface {
+	Node
+}
+
+// WildcardPattern matches any value without binding it
+type WildcardPattern struct {
+	Token token.Token // the _ identifier
+}
+
+func (wp *WildcardPattern) TokenLiteral() string {
+	return wp.Token.Literal
+}
+
+func (wp *WildcardPattern) Pos() token.Position {
+	return wp.Token.Pos()
+}
+
+func (wp *WildcardPattern) String() string {
+	return "_"
+}
+
+// ArrayPattern matches an array with as many elements as Elements matching
+// them. With a Rest the array can be longer, Rest is bound to the elements
+// left.
+type ArrayPattern struct {
+	Token    token.Token // the token.LBRACKET token
+	Elements []Pattern
+	Rest     *Identifier // nil when the pattern has no ...rest
+}
+
+func (ap *ArrayPattern) TokenLiteral() string {
+	return ap.Token.Literal
+}
+
+func (ap *ArrayPattern) Pos() token.Position {
+	return ap.Token.Pos()
+}
+
+func (ap *ArrayPattern) String() string {
+	elements := []string{}
+	for _, el := range ap.Elements {
+		elements = append(elements, el.String())
+	}
+	if ap.Rest != nil {
+		elements = append(elements, "..."+ap.Rest.String())
+	}
+
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// HashPatternPair is a key of a hash pattern and the pattern its value must
+// match
+type HashPatternPair struct {
+	Key   Expression
+	Value Pattern
+}
+
+// HashPattern matches a hash holding all the keys of Pairs, other keys are
+// ignored. A name used as a key is a string key, alone it also binds the
+// value: {name} is {"name": name}.
+type HashPattern struct {
+	Token token.Token // the token.LBRACE token
+	Pairs []HashPatternPair
+}
+
+func (hp *HashPattern) TokenLiteral() string {
+	return hp.Token.Literal
+}
+
+func (hp *HashPattern) Pos() token.Position {
+	return hp.Token.Pos()
+}
+
+func (hp *HashPattern) String() string {
+	pairs := []string{}
+	for _, pair := range hp.Pairs {
+		key := pair.Key.String()
+		if lit, ok := pair.Key.(*StringLiteral); ok && lit.Token.Type == token.IDENT {
+			key = lit.Value
+			if id, ok := pair.Value.(*Identifier); ok && id.Value == lit.Value {
+				pairs = append(pairs, key)
+				continue
+			}
+		}
+		pairs = append(pairs, key+": "+pair.Value.String())
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// MatchArm runs Body when its pattern matches and Guard, if any, is truthy
+type MatchArm struct {
+	Pattern Pattern
+	Guard   Expression // nil when the arm has no if guard
+	Body    *BlockStatement
+}
+
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ma.Pattern.String())
+	if ma.Guard != nil {
+		out.WriteString(" if " + ma.Guard.String())
+	}
+	out.WriteString(" => {" + ma.Body.String() + "}")
+
+	return out.String()
+}
+
+// MatchExpression evaluates to the body of the first arm matching Subject
+type MatchExpression struct {
+	Token   token.Token // the token.MATCH token
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+func (me *MatchExpression) TokenLiteral() string {
+	return me.Token.Literal
+}
+
+func (me *MatchExpression) Pos() token.Position {
+	return me.Token.Pos()
+}
+
+func (me *MatchExpression) String() string {
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+
+	return "match (" + me.Subject.String() + ") {" + strings.Join(arms, ", ") + "}"
+}
+
+// PatternNames returns the identifiers a pattern binds, in source order
+func PatternNames(pattern Pattern) []*Identifier {
+	switch pattern := pattern.(type) {
+	case *Identifier:
+		return []*Identifier{pattern}
+	case *ArrayPattern:
+		names := []*Identifier{}
+		for _, el := range pattern.Elements {
+			names = append(names, PatternNames(el)...)
+		}
+		if pattern.Rest != nil && pattern.Rest.Value != "_" {
+			names = append(names, pattern.Rest)
+		}
+		return names
+	case *HashPattern:
+		names := []*Identifier{}
+		for _, pair := range pattern.Pairs {
+			names = append(names, PatternNames(pair.Value)...)
+		}
+		return names
+	}
+
+	return nil
+}
@@ -8,15 +8,25 @@ import (
 )
 
 type LetStatement struct {
-	Token token.Token // the token.LET token
-	Name  *Identifier
+	Token token.Token // the token.LET or token.CONST token
+	Name  *Declaration
 	Value Expression
 }
 
+// Constant reports whether the statement declares a constant, a variable
+// that can't be assigned to
+func (ls *LetStatement) Constant() bool {
+	return ls.Token.Type == token.CONST
+}
+
 func (ls *LetStatement) TokenLiteral() string {
 	return ls.Token.Literal
 }
 
+func (ls *LetStatement) Pos() token.Position {
+	return ls.Token.Pos()
+}
+
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
@@ -42,6 +52,10 @@ func (r *ReturnStatement) TokenLiteral() string {
 	return r.Token.Literal
 }
 
+func (r *ReturnStatement) Pos() token.Position {
+	return r.Token.Pos()
+}
+
 func (r *ReturnStatement) String() string {
 	var out bytes.Buffer
 
@@ -65,6 +79,10 @@ func (es *ExpressionStatement) TokenLiteral() string {
 	return es.Token.Literal
 }
 
+func (es *ExpressionStatement) Pos() token.Position {
+	return es.Token.Pos()
+}
+
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
 		return es.Expression.String()
@@ -82,6 +100,10 @@ func (bs *BlockStatement) TokenLiteral() string {
 	return bs.Token.Literal
 }
 
+func (bs *BlockStatement) Pos() token.Position {
+	return bs.Token.Pos()
+}
+
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
 
@@ -94,7 +116,8 @@ func (bs *BlockStatement) String() string {
 type FunctionStatement struct {
 	Name       string
 	Token      token.Token
-	Parameters []*Identifier
+	Parameters []*Declaration
+	ReturnType TypeExpression // nil when the return type is not annotated
 	Body       *BlockStatement
 }
 
@@ -102,6 +125,10 @@ func (fl *FunctionStatement) TokenLiteral() string {
 	return fl.Token.Literal
 }
 
+func (fl *FunctionStatement) Pos() token.Position {
+	return fl.Token.Pos()
+}
+
 func (fl *FunctionStatement) String() string {
 	var out bytes.Buffer
 
@@ -114,7 +141,67 @@ func (fl *FunctionStatement) String() string {
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
+	if fl.ReturnType != nil {
+		out.WriteString("-> " + fl.ReturnType.String() + " ")
+	}
 	out.WriteString(fl.Body.String())
 
 	return out.String()
 }
+
+type BreakStatement struct {
+	Token token.Token
+	Label *Identifier // nil for the innermost loop
+}
+
+func (bs *BreakStatement) TokenLiteral() string {
+	return bs.Token.Literal
+}
+
+func (bs *BreakStatement) Pos() token.Position {
+	return bs.Token.Pos()
+}
+
+func (bs *BreakStatement) String() string {
+	if bs.Label != nil {
+		return bs.TokenLiteral() + " " + bs.Label.String() + ";"
+	}
+	return bs.TokenLiteral() + ";"
+}
+
+type ContinueStatement struct {
+	Token token.Token
+	Label *Identifier // nil for the innermost loop
+}
+
+func (cs *ContinueStatement) TokenLiteral() string {
+	return cs.Token.Literal
+}
+
+func (cs *ContinueStatement) Pos() token.Position {
+	return cs.Token.Pos()
+}
+
+func (cs *ContinueStatement) String() string {
+	if cs.Label != nil {
+		return cs.TokenLiteral() + " " + cs.Label.String() + ";"
+	}
+	return cs.TokenLiteral() + ";"
+}
+
+type ThrowStatement struct {
+	Token token.Token
+	Value Expression
+}
+
+func (ts *ThrowStatement) TokenLiteral() string {
+	return ts.Token.Literal
+}
+
+func (ts *ThrowStatement) Pos() token.Position {
+	return ts.Token.Pos()
+}
+
+func (ts *ThrowStatement) String() string {
+	return ts.TokenLiteral() + " " + ts.Value.String() + ";"
+}
@@ -0,0 +1,87 @@
+package ast
+
+import (
+	"strings"
+
+	"github.com/rumpl/monkey-lang/token"
+)
+
+// StructStatement declares a record type with named fields
+type StructStatement struct {
+	Token  token.Token // the token.STRUCT token
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+func (ss *StructStatement) TokenLiteral() string {
+	return ss.Token.Literal
+}
+
+func (ss *StructStatement) Pos() token.Position {
+	return ss.Token.Pos()
+}
+
+func (ss *StructStatement) String() string {
+	fields := []string{}
+	for _, f := range ss.Fields {
+		fields = append(fields, f.Value)
+	}
+
+	return "struct " + ss.Name.Value + " { " + strings.Join(fields, ", ") + " }"
+}
+
+// StructField is the value given to a field in a struct literal
+type StructField struct {
+	Name  *Identifier
+	Value Expression
+}
+
+// StructLiteral builds a value of the struct type Name, every field of the
+// type must be given a value
+type StructLiteral struct {
+	Token  token.Token // the token.LBRACE token
+	Name   *Identifier
+	Fields []*StructField
+}
+
+func (sl *StructLiteral) TokenLiteral() string {
+	return sl.Token.Literal
+}
+
+func (sl *StructLiteral) Pos() token.Position {
+	return sl.Name.Pos()
+}
+
+func (sl *StructLiteral) String() string {
+	fields := []string{}
+	for _, f := range sl.Fields {
+		fields = append(fields, f.Name.Value+": "+f.Value.String())
+	}
+
+	return sl.Name.Value + " {" + strings.Join(fields, ", ") + "}"
+}
+
+// ImplStatement attaches methods to the struct type Name. The first
+// parameter of a method is the receiver, the value the method is called on.
+type ImplStatement struct {
+	Token   token.Token // the token.IMPL token
+	Name    *Identifier
+	Methods []*FunctionStatement
+}
+
+func (is *ImplStatement) TokenLiteral() string {
+	return is.Token.Literal
+}
+
+func (is *ImplStatement) Pos() token.Position {
+	return is.Token.Pos()
+}
+
+func (is *ImplStatement) String() string {
+	methods := []string{}
+	for _, m := range is.Methods {
+		methods = append(methods, "fn "+m.Name+strings.TrimPrefix(m.String(), m.TokenLiteral()))
+	}
+
+	return "impl " + is.Name.Value + " { " + strings.Join(methods, " ") + " }"
+}
@@ -0,0 +1,62 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/rumpl/monkey-lang/token"
+)
+
+// TypeExpression is a type annotation
+type TypeExpression interface {
+	Node
+}
+
+// NamedType is a type referred to by its name, like int or bool
+type NamedType struct {
+	Token token.Token // the token.IDENT token
+	Name  string
+}
+
+func (nt *NamedType) TokenLiteral() string {
+	return nt.Token.Literal
+}
+
+func (nt *NamedType) Pos() token.Position {
+	return nt.Token.Pos()
+}
+
+func (nt *NamedType) String() string {
+	return nt.Name
+}
+
+// FunctionType is the type of a function, like fn(int, int) -> bool
+type FunctionType struct {
+	Token      token.Token // the token.FUNCTION token
+	Parameters []TypeExpression
+	Return     TypeExpression
+}
+
+func (ft *FunctionType) TokenLiteral() string {
+	return ft.Token.Literal
+}
+
+func (ft *FunctionType) Pos() token.Position {
+	return ft.Token.Pos()
+}
+
+func (ft *FunctionType) String() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range ft.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString("fn(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") -> ")
+	out.WriteString(ft.Return.String())
+
+	return out.String()
+}
@@ -1,24 +1,39 @@
 package codegen
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os/exec"
+	"strings"
 
 	"github.com/rumpl/monkey-lang/ast"
 	"github.com/rumpl/monkey-lang/object"
+	"github.com/rumpl/monkey-lang/types"
 	"tinygo.org/x/go-llvm"
 )
 
 type CG struct {
+	// Output is the path of the linked binary, the object file is written
+	// next to it with a .o extension
+	Output string
+
 	program       ast.Node
 	targetMachine llvm.TargetMachine
 	builder       llvm.Builder
 	mod           llvm.Module
+
+	info *types.Info
+	// vars holds the stack slots of the variables in scope
+	vars     map[string]llvm.Value
+	function llvm.Value
+	loops    []loop
+	errors   []string
 }
 
 func New(program ast.Node) *CG {
 	return &CG{
+		Output:  "out",
 		program: program,
 	}
 }
@@ -52,10 +67,25 @@ func (c *CG) Codegen(env *object.Environment) error {
 	passManager.AddGVNPass()
 	passManager.AddReassociatePass()
 
+	program, ok := c.program.(*ast.Program)
+	if !ok {
+		return fmt.Errorf("cannot compile %T, expected a program", c.program)
+	}
+
+	info, errs := types.Check(program)
+	if len(errs) != 0 {
+		return fmt.Errorf("%s", errs[0])
+	}
+	c.info = info
+	c.vars = map[string]llvm.Value{}
+
 	c.builder = llvm.NewBuilder()
 	c.mod = llvm.NewModule("main")
 
-	c.codegen(c.program, env)
+	c.codegen(program, env)
+	if len(c.errors) != 0 {
+		return errors.New(strings.Join(c.errors, "\n"))
+	}
 	if ok := llvm.VerifyModule(c.mod, llvm.PrintMessageAction); ok != nil {
 		fmt.Println(ok.Error())
 	}
@@ -64,9 +94,10 @@ func (c *CG) Codegen(env *object.Environment) error {
 	c.mod.Dump()
 
 	llvmBuf, _ := c.targetMachine.EmitToMemoryBuffer(c.mod, llvm.ObjectFile)
-	_ = ioutil.WriteFile("out.o", llvmBuf.Bytes(), 0666)
+	objectFile := c.Output + ".o"
+	_ = ioutil.WriteFile(objectFile, llvmBuf.Bytes(), 0666)
 
-	return exec.Command("cc", "out.o", "-fno-PIE", "-lc", "-o", "out").Run()
+	return exec.Command("cc", objectFile, "-fno-PIE", "-lc", "-o", c.Output).Run()
 }
 
 func (c *CG) codegen(node ast.Node, env *object.Environment) llvm.Value {
@@ -76,55 +107,338 @@ func (c *CG) codegen(node ast.Node, env *object.Environment) llvm.Value {
 	case *ast.BlockStatement:
 		return c.codegenBlockStatement(node, env)
 	case *ast.CallExpression:
-		return c.builder.CreateCall(c.mod.NamedFunction(node.Function.String()), []llvm.Value{}, node.Function.String())
+		return c.codegenCallExpression(node, env)
 	case *ast.FunctionStatement:
-		main := llvm.FunctionType(llvm.Int32Type(), []llvm.Type{}, false)
-		name := node.Name
-		entry := name
-		if name == "main" {
-			entry = "entry"
+		v := c.mod.NamedFunction(node.Name)
+		if v.IsNil() {
+			v = c.declareFunction(node)
+		}
+		if v.IsNil() {
+			return v
 		}
-		v := llvm.AddFunction(c.mod, name, main)
 
-		fmt.Println(name, entry)
-		block := llvm.AddBasicBlock(c.mod.NamedFunction(name), entry)
-		c.builder.SetInsertPoint(block, block.FirstInstruction())
+		entry := node.Name
+		if node.Name == "main" {
+			entry = "entry"
+		}
 
-		c.codegen(node.Body, env)
+		c.codegenFunctionBody(v, entry, node.Parameters, node.Body, env)
 
 		return v
 	case *ast.FunctionLiteral:
 		main := llvm.FunctionType(llvm.Int32Type(), []llvm.Type{}, false)
 		v := llvm.AddFunction(c.mod, "main", main)
-		block := llvm.AddBasicBlock(c.mod.NamedFunction("main"), "entry")
-		c.builder.SetInsertPoint(block, block.FirstInstruction())
 
-		c.codegen(node.Body, env)
+		c.codegenFunctionBody(v, "entry", node.Parameters, node.Body, env)
 
 		return v
 	case *ast.ExpressionStatement:
 		return c.codegen(node.Expression, env)
+	case *ast.LetStatement:
+		id := node.Name.Variable()
+		if id == nil {
+			c.errorf(node.Name, "cannot compile the destructuring %s", node.Name)
+			return llvm.Value{}
+		}
+		val := c.codegenValue(node.Value, env)
+		if val.IsNil() {
+			return val
+		}
+		ptr := c.alloca(c.llvmType(id, c.info.TypeOf(id)), id.Value)
+		c.builder.CreateStore(val, ptr)
+		c.vars[id.Value] = ptr
+		return llvm.Value{}
+	case *ast.Identifier:
+		ptr, ok := c.vars[node.Value]
+		if !ok {
+			c.errorf(node, "undefined: %s", node.Value)
+			return llvm.Value{}
+		}
+		return c.builder.CreateLoad(ptr, node.Value)
+	case *ast.AssignExpression:
+		left, ok := node.Left.(*ast.Identifier)
+		if !ok {
+			c.errorf(node, "cannot compile the assignment to %s", node.Left)
+			return llvm.Value{}
+		}
+		ptr, ok := c.vars[left.Value]
+		if !ok {
+			c.errorf(node, "undefined: %s", left.Value)
+			return llvm.Value{}
+		}
+		val := c.codegenValue(node.Expression, env)
+		if !val.IsNil() {
+			c.builder.CreateStore(val, ptr)
+		}
+		return val
+	case *ast.PrefixExpression:
+		right := c.codegenValue(node.Right, env)
+		switch node.Operator {
+		case "-":
+			if c.info.TypeOf(node.Right) == types.Float {
+				return c.builder.CreateFNeg(right, "")
+			}
+			return c.builder.CreateNeg(right, "")
+		case "!":
+			if t := c.info.TypeOf(node.Right); t == types.Int || t == types.Float {
+				// Numbers are always truthy, zero included
+				return llvm.ConstInt(llvm.Int1Type(), 0, false)
+			}
+			return c.builder.CreateNot(right, "")
+		case "~":
+			return c.builder.CreateNot(right, "")
+		}
+	case *ast.IfExpression:
+		return c.codegenIfExpression(node, env)
+	case *ast.WhileExpression:
+		c.codegenWhileExpression(node, env)
+	case *ast.ForExpression:
+		c.codegenForExpression(node, env)
+	case *ast.BreakStatement:
+		if l := c.findLoop(node, node.Label); l != nil {
+			c.builder.CreateBr(l.exit)
+		}
+	case *ast.ContinueStatement:
+		if l := c.findLoop(node, node.Label); l != nil {
+			c.builder.CreateBr(l.next)
+		}
 	case *ast.InfixExpression:
-		left := c.codegen(node.Left, env)
-		right := c.codegen(node.Right, env)
+		if node.Operator == "&&" || node.Operator == "||" {
+			return c.codegenLogicalExpression(node, env)
+		}
+
+		left := c.codegenValue(node.Left, env)
+		right := c.codegenValue(node.Right, env)
+		if left.IsNil() || right.IsNil() {
+			return llvm.Value{}
+		}
+
+		if c.info.TypeOf(node.Left) == types.Float || c.info.TypeOf(node.Right) == types.Float {
+			return c.codegenFloatInfixExpression(node.Operator, c.toDouble(left), c.toDouble(right))
+		}
 		return c.codegenInfixExpression(node.Operator, left, right)
 	case *ast.ReturnStatement:
-		val := c.codegen(node.ReturnValue, env)
-		c.builder.CreateRet(val)
+		val := c.codegenValue(node.ReturnValue, env)
+		if !val.IsNil() {
+			c.builder.CreateRet(c.returnValue(val))
+		}
 		return val
 	case *ast.IntegerLiteral:
-		b := c.builder.CreateAlloca(llvm.Int32Type(), "")
-		c.builder.CreateStore(llvm.ConstInt(llvm.Int32Type(), uint64(node.Value), false), b)
-		return b
+		return llvm.ConstInt(llvm.Int32Type(), uint64(node.Value), false)
+	case *ast.FloatLiteral:
+		return llvm.ConstFloat(llvm.DoubleType(), node.Value)
+	case *ast.Boolean:
+		if node.Value {
+			return llvm.ConstInt(llvm.Int1Type(), 1, false)
+		}
+		return llvm.ConstInt(llvm.Int1Type(), 0, false)
+	default:
+		c.errorf(node, "cannot compile %s", node)
 	}
 
 	return llvm.Value{}
 }
 
+// codegenValue compiles an expression whose value is used
+func (c *CG) codegenValue(node ast.Expression, env *object.Environment) llvm.Value {
+	v := c.codegen(node, env)
+	if v.IsNil() && len(c.errors) == 0 {
+		c.errorf(node, "%s has no value", node)
+	}
+	return v
+}
+
+func (c *CG) errorf(node ast.Node, format string, a ...interface{}) {
+	msg := fmt.Sprintf("%s: %s", node.Pos(), fmt.Sprintf(format, a...))
+	// The functions declared before their code is generated can report the
+	// same error twice
+	for _, err := range c.errors {
+		if err == msg {
+			return
+		}
+	}
+	c.errors = append(c.errors, msg)
+}
+
+// llvmType returns the native type of the values of type t. The checker
+// knows the type of integers, floats and booleans, they are compiled to
+// plain machine values instead of monkey objects.
+func (c *CG) llvmType(node ast.Node, t types.Type) llvm.Type {
+	switch t {
+	case types.Int:
+		return llvm.Int32Type()
+	case types.Float:
+		return llvm.DoubleType()
+	case types.Bool:
+		return llvm.Int1Type()
+	}
+
+	c.errorf(node, "cannot compile a value of type %s, add a type annotation", t)
+	return llvm.Int32Type()
+}
+
+// declareFunction adds the signature of a named function to the module so it
+// can be called before it is defined
+func (c *CG) declareFunction(node *ast.FunctionStatement) llvm.Value {
+	f, ok := c.info.TypeOf(node).(*types.Function)
+	if !ok {
+		c.errorf(node, "unknown type for function %s", node.Name)
+		return llvm.Value{}
+	}
+
+	// A parameter without a name has no variable to live in
+	for _, param := range node.Parameters {
+		if param.Variable() == nil || param.Default != nil || param.Rest {
+			c.errorf(param, "cannot compile the parameter %s", param)
+			return llvm.Value{}
+		}
+	}
+
+	params := []llvm.Type{}
+	for i, p := range f.Params {
+		params = append(params, c.llvmType(node.Parameters[i], p))
+	}
+
+	ret := c.llvmType(node, f.Return)
+	if node.Name == "main" {
+		// The value returned by main is the exit code of the program
+		ret = llvm.Int32Type()
+	}
+
+	return llvm.AddFunction(c.mod, node.Name, llvm.FunctionType(ret, params, false))
+}
+
+func (c *CG) codegenFunctionBody(fn llvm.Value, entry string, params []*ast.Declaration, body *ast.BlockStatement, env *object.Environment) {
+	outer := c.vars
+	c.vars = map[string]llvm.Value{}
+	for name, v := range outer {
+		c.vars[name] = v
+	}
+
+	block := llvm.AddBasicBlock(fn, entry)
+	c.builder.SetInsertPoint(block, block.FirstInstruction())
+	c.function = fn
+
+	// Parameters live on the stack like the other variables, so they can be
+	// assigned to
+	for i, p := range params {
+		id := p.Variable()
+		ptr := c.alloca(fn.Param(i).Type(), id.Value)
+		c.builder.CreateStore(fn.Param(i), ptr)
+		c.vars[id.Value] = ptr
+	}
+
+	errs := len(c.errors)
+	result := c.codegen(body, env)
+
+	// The value of the last expression is returned when the body doesn't end
+	// with a return statement
+	if !c.terminated() && len(c.errors) == errs {
+		if result.IsNil() {
+			c.errorf(body, "missing return value")
+		} else {
+			c.builder.CreateRet(c.returnValue(result))
+		}
+	}
+
+	c.vars = outer
+}
+
+// returnValue converts val to the return type of the current function, main
+// returns a boolean or a float as an exit code
+func (c *CG) returnValue(val llvm.Value) llvm.Value {
+	ret := c.function.Type().ElementType().ReturnType()
+	if val.Type() == ret {
+		return val
+	}
+
+	switch val.Type() {
+	case llvm.Int1Type():
+		return c.builder.CreateZExt(val, ret, "")
+	case llvm.DoubleType():
+		return c.builder.CreateFPToSI(val, ret, "")
+	}
+	return val
+}
+
+// toDouble promotes an integer to a float
+func (c *CG) toDouble(val llvm.Value) llvm.Value {
+	if val.Type() == llvm.DoubleType() {
+		return val
+	}
+	return c.builder.CreateSIToFP(val, llvm.DoubleType(), "")
+}
+
+func (c *CG) codegenCallExpression(node *ast.CallExpression, env *object.Environment) llvm.Value {
+	fn := c.mod.NamedFunction(node.Function.String())
+	if fn.IsNil() {
+		return c.codegenConversion(node, env)
+	}
+
+	args := []llvm.Value{}
+	for _, arg := range node.Arguments {
+		switch arg.(type) {
+		case *ast.SpreadExpression, *ast.KeywordArgument:
+			c.errorf(arg, "cannot compile the argument %s", arg)
+			return llvm.Value{}
+		}
+		v := c.codegenValue(arg, env)
+		if v.IsNil() {
+			return v
+		}
+		args = append(args, v)
+	}
+
+	return c.builder.CreateCall(fn, args, "")
+}
+
+// codegenConversion compiles a call to the int and float builtins
+func (c *CG) codegenConversion(node *ast.CallExpression, env *object.Environment) llvm.Value {
+	name := node.Function.String()
+	if (name != "int" && name != "float") || len(node.Arguments) != 1 {
+		c.errorf(node, "cannot call %s", node.Function)
+		return llvm.Value{}
+	}
+
+	v := c.codegenValue(node.Arguments[0], env)
+	if v.IsNil() {
+		return v
+	}
+
+	switch t := c.info.TypeOf(node.Arguments[0]); {
+	case t == types.Float && name == "int":
+		return c.builder.CreateFPToSI(v, llvm.Int32Type(), "")
+	case t == types.Int && name == "float":
+		return c.builder.CreateSIToFP(v, llvm.DoubleType(), "")
+	case t == types.Int || t == types.Float:
+		return v
+	default:
+		c.errorf(node, "cannot convert a value of type %s to %s", t, name)
+		return llvm.Value{}
+	}
+}
+
 func (c *CG) codegenProgram(program *ast.Program, env *object.Environment) llvm.Value {
 	var result llvm.Value
 
 	for _, stmt := range program.Statements {
+		if fs, ok := stmt.(*ast.FunctionStatement); ok {
+			c.declareFunction(fs)
+		}
+	}
+
+	for _, stmt := range program.Statements {
+		switch stmt := stmt.(type) {
+		case *ast.FunctionStatement:
+		case *ast.ExpressionStatement:
+			if _, ok := stmt.Expression.(*ast.FunctionLiteral); !ok {
+				c.errorf(stmt, "only functions can be compiled at the top level")
+				continue
+			}
+		default:
+			c.errorf(stmt, "only functions can be compiled at the top level")
+			continue
+		}
 		result = c.codegen(stmt, env)
 	}
 
@@ -132,29 +446,100 @@ func (c *CG) codegenProgram(program *ast.Program, env *object.Environment) llvm.
 }
 
 func (c *CG) codegenBlockStatement(block *ast.BlockStatement, env *object.Environment) llvm.Value {
+	// The variables declared in a block are only visible in it
+	outer := c.vars
+	c.vars = map[string]llvm.Value{}
+	for name, v := range outer {
+		c.vars[name] = v
+	}
+
 	var result llvm.Value
 
 	for _, stmt := range block.Statements {
+		// The code following a return, break or continue never runs
+		if c.terminated() {
+			break
+		}
+		// The statements following an error would only report its
+		// consequences, like the variables it failed to declare
+		errs := len(c.errors)
 		result = c.codegen(stmt, env)
+		if len(c.errors) > errs {
+			break
+		}
 	}
 
+	c.vars = outer
 	return result
 }
 
 func (c *CG) codegenInfixExpression(operator string, left llvm.Value, right llvm.Value) llvm.Value {
-	aVal := c.builder.CreateLoad(left, "")
-	bVal := c.builder.CreateLoad(right, "")
+	var result llvm.Value
+	switch operator {
+	case "+":
+		result = c.builder.CreateAdd(left, right, "")
+	case "-":
+		result = c.builder.CreateSub(left, right, "")
+	case "*":
+		result = c.builder.CreateMul(left, right, "")
+	case "/":
+		result = c.builder.CreateSDiv(left, c.checkDivisor(operator, left, right), "")
+	case "%":
+		result = c.builder.CreateSRem(left, c.checkDivisor(operator, left, right), "")
+	case "&":
+		result = c.builder.CreateAnd(left, right, "")
+	case "|":
+		result = c.builder.CreateOr(left, right, "")
+	case "^":
+		result = c.builder.CreateXor(left, right, "")
+	case "<<":
+		result = c.builder.CreateShl(left, right, "")
+	case ">>":
+		result = c.builder.CreateAShr(left, right, "")
+	case ">>>":
+		result = c.builder.CreateLShr(left, right, "")
+	case "<":
+		result = c.builder.CreateICmp(llvm.IntSLT, left, right, "")
+	case ">":
+		result = c.builder.CreateICmp(llvm.IntSGT, left, right, "")
+	case "<=":
+		result = c.builder.CreateICmp(llvm.IntSLE, left, right, "")
+	case ">=":
+		result = c.builder.CreateICmp(llvm.IntSGE, left, right, "")
+	case "==":
+		result = c.builder.CreateICmp(llvm.IntEQ, left, right, "")
+	case "!=":
+		result = c.builder.CreateICmp(llvm.IntNE, left, right, "")
+	}
+
+	return result
+}
 
+func (c *CG) codegenFloatInfixExpression(operator string, left llvm.Value, right llvm.Value) llvm.Value {
 	var result llvm.Value
 	switch operator {
 	case "+":
-		result = c.builder.CreateAdd(aVal, bVal, "")
+		result = c.builder.CreateFAdd(left, right, "")
 	case "-":
-		result = c.builder.CreateSub(aVal, bVal, "")
+		result = c.builder.CreateFSub(left, right, "")
 	case "*":
-		result = c.builder.CreateMul(aVal, bVal, "")
+		result = c.builder.CreateFMul(left, right, "")
 	case "/":
-		result = c.builder.CreateFDiv(aVal, bVal, "")
+		result = c.builder.CreateFDiv(left, c.checkFloatDivisor(right), "")
+	case "%":
+		result = c.builder.CreateFRem(left, c.checkFloatDivisor(right), "")
+	case "<":
+		result = c.builder.CreateFCmp(llvm.FloatOLT, left, right, "")
+	case ">":
+		result = c.builder.CreateFCmp(llvm.FloatOGT, left, right, "")
+	case "<=":
+		result = c.builder.CreateFCmp(llvm.FloatOLE, left, right, "")
+	case ">=":
+		result = c.builder.CreateFCmp(llvm.FloatOGE, left, right, "")
+	case "==":
+		result = c.builder.CreateFCmp(llvm.FloatOEQ, left, right, "")
+	case "!=":
+		result = c.builder.CreateFCmp(llvm.FloatUNE, left, right, "")
 	}
 
 	return result
@@ -0,0 +1,224 @@
+package codegen
+
+import (
+	"github.com/rumpl/monkey-lang/ast"
+	"github.com/rumpl/monkey-lang/object"
+	"github.com/rumpl/monkey-lang/types"
+	"tinygo.org/x/go-llvm"
+)
+
+// loop is a loop being compiled, break jumps to exit and continue to next
+type loop struct {
+	label string
+	next  llvm.BasicBlock
+	exit  llvm.BasicBlock
+}
+
+// alloca reserves a stack slot in the entry block of the current function so
+// that variables declared in a loop don't grow the stack
+func (c *CG) alloca(t llvm.Type, name string) llvm.Value {
+	b := llvm.NewBuilder()
+	defer b.Dispose()
+
+	entry := c.function.EntryBasicBlock()
+	if first := entry.FirstInstruction(); first.IsNil() {
+		b.SetInsertPointAtEnd(entry)
+	} else {
+		b.SetInsertPointBefore(first)
+	}
+
+	return b.CreateAlloca(t, name)
+}
+
+// terminated tells whether the current block already ends, the code added
+// after a return or a jump would never run
+func (c *CG) terminated() bool {
+	last := c.builder.GetInsertBlock().LastInstruction()
+	if last.IsNil() {
+		return false
+	}
+
+	return !last.IsAReturnInst().IsNil() || !last.IsABranchInst().IsNil() || !last.IsAUnreachableInst().IsNil()
+}
+
+// condition compiles exp to an i1, only false and null are falsy
+func (c *CG) condition(exp ast.Expression, env *object.Environment) llvm.Value {
+	v := c.codegenValue(exp, env)
+
+	switch c.info.TypeOf(exp) {
+	case types.Bool:
+		return v
+	case types.Int, types.Float:
+		return llvm.ConstInt(llvm.Int1Type(), 1, false)
+	}
+
+	c.errorf(exp, "cannot compile a condition of type %s", c.info.TypeOf(exp))
+	return llvm.ConstInt(llvm.Int1Type(), 0, false)
+}
+
+func (c *CG) codegenIfExpression(node *ast.IfExpression, env *object.Environment) llvm.Value {
+	cond := c.condition(node.Condition, env)
+
+	then := llvm.AddBasicBlock(c.function, "if.then")
+	els := llvm.AddBasicBlock(c.function, "if.else")
+	end := llvm.AddBasicBlock(c.function, "if.end")
+	c.builder.CreateCondBr(cond, then, els)
+
+	// open counts the branches that reach the end of the if
+	open := 0
+	values := []llvm.Value{}
+	blocks := []llvm.BasicBlock{}
+
+	branch := func(block *ast.BlockStatement, bb llvm.BasicBlock) {
+		c.builder.SetInsertPointAtEnd(bb)
+
+		var v llvm.Value
+		if block != nil {
+			v = c.codegen(block, env)
+		}
+
+		if c.terminated() {
+			return
+		}
+
+		open++
+		if !v.IsNil() {
+			values = append(values, v)
+			blocks = append(blocks, c.builder.GetInsertBlock())
+		}
+		c.builder.CreateBr(end)
+	}
+
+	branch(node.Consequence, then)
+	branch(node.Alternative, els)
+
+	c.builder.SetInsertPointAtEnd(end)
+
+	if open == 0 {
+		// Both branches return or jump
+		c.builder.CreateUnreachable()
+		return llvm.Value{}
+	}
+
+	// The if is only a value when its branches are of the same known type
+	t := c.info.TypeOf(node)
+	if len(values) != open || (t != types.Int && t != types.Float && t != types.Bool) {
+		return llvm.Value{}
+	}
+
+	phi := c.builder.CreatePHI(values[0].Type(), "")
+	phi.AddIncoming(values, blocks)
+
+	return phi
+}
+
+// codegenLogicalExpression compiles && and || to a branch, the right operand
+// is only computed when the left one doesn't decide the result
+func (c *CG) codegenLogicalExpression(node *ast.InfixExpression, env *object.Environment) llvm.Value {
+	left := c.condition(node.Left, env)
+	from := c.builder.GetInsertBlock()
+
+	rhs := llvm.AddBasicBlock(c.function, "logic.rhs")
+	end := llvm.AddBasicBlock(c.function, "logic.end")
+
+	// The value of the expression when the right operand is skipped
+	short := llvm.ConstInt(llvm.Int1Type(), 0, false)
+	if node.Operator == "||" {
+		short = llvm.ConstInt(llvm.Int1Type(), 1, false)
+		c.builder.CreateCondBr(left, end, rhs)
+	} else {
+		c.builder.CreateCondBr(left, rhs, end)
+	}
+
+	c.builder.SetInsertPointAtEnd(rhs)
+	right := c.condition(node.Right, env)
+	rhs = c.builder.GetInsertBlock()
+	c.builder.CreateBr(end)
+
+	c.builder.SetInsertPointAtEnd(end)
+	phi := c.builder.CreatePHI(llvm.Int1Type(), "")
+	phi.AddIncoming([]llvm.Value{short, right}, []llvm.BasicBlock{from, rhs})
+
+	return phi
+}
+
+func (c *CG) codegenWhileExpression(node *ast.WhileExpression, env *object.Environment) {
+	cond := llvm.AddBasicBlock(c.function, "while.cond")
+	body := llvm.AddBasicBlock(c.function, "while.body")
+	exit := llvm.AddBasicBlock(c.function, "while.exit")
+
+	c.builder.CreateBr(cond)
+	c.builder.SetInsertPointAtEnd(cond)
+	c.builder.CreateCondBr(c.condition(node.Condition, env), body, exit)
+
+	c.builder.SetInsertPointAtEnd(body)
+	c.codegenLoopBody(loop{label: labelName(node.Label), next: cond, exit: exit}, node.Body, env)
+
+	c.builder.SetInsertPointAtEnd(exit)
+}
+
+func (c *CG) codegenForExpression(node *ast.ForExpression, env *object.Environment) {
+	// The variable declared by the loop is only visible in the loop
+	outer := c.vars
+	c.vars = map[string]llvm.Value{}
+	for name, v := range outer {
+		c.vars[name] = v
+	}
+
+	c.codegen(node.Initial, env)
+
+	cond := llvm.AddBasicBlock(c.function, "for.cond")
+	body := llvm.AddBasicBlock(c.function, "for.body")
+	inc := llvm.AddBasicBlock(c.function, "for.inc")
+	exit := llvm.AddBasicBlock(c.function, "for.exit")
+
+	c.builder.CreateBr(cond)
+	c.builder.SetInsertPointAtEnd(cond)
+	c.builder.CreateCondBr(c.condition(node.StopCondition, env), body, exit)
+
+	c.builder.SetInsertPointAtEnd(body)
+	c.codegenLoopBody(loop{label: labelName(node.Label), next: inc, exit: exit}, node.Statements, env)
+
+	c.builder.SetInsertPointAtEnd(inc)
+	c.codegen(node.Increment, env)
+	c.builder.CreateBr(cond)
+
+	c.builder.SetInsertPointAtEnd(exit)
+	c.vars = outer
+}
+
+// codegenLoopBody compiles the body of l, the body goes on with the next
+// iteration when it finishes
+func (c *CG) codegenLoopBody(l loop, body *ast.BlockStatement, env *object.Environment) {
+	c.loops = append(c.loops, l)
+	c.codegen(body, env)
+	c.loops = c.loops[:len(c.loops)-1]
+
+	if !c.terminated() {
+		c.builder.CreateBr(l.next)
+	}
+}
+
+// findLoop returns the loop a break or continue jumps to, the innermost one
+// when there is no label
+func (c *CG) findLoop(node ast.Node, label *ast.Identifier) *loop {
+	for i := len(c.loops) - 1; i >= 0; i-- {
+		if label == nil || c.loops[i].label == label.Value {
+			return &c.loops[i]
+		}
+	}
+
+	if label == nil {
+		c.errorf(node, "%s outside of a loop", node.TokenLiteral())
+	} else {
+		c.errorf(node, "%s to unknown label %s", node.TokenLiteral(), label.Value)
+	}
+	return nil
+}
+
+func labelName(label *ast.Identifier) string {
+	if label == nil {
+		return ""
+	}
+	return label.Value
+}
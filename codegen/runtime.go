@@ -0,0 +1,76 @@
+package codegen
+
+import (
+	"tinygo.org/x/go-llvm"
+)
+
+// runtimeError ends the program with msg like the evaluator reports an
+// error: the message is written to the standard error and the program exits
+// with the status 1
+func (c *CG) runtimeError(msg string) {
+	write := c.mod.NamedFunction("write")
+	if write.IsNil() {
+		params := []llvm.Type{llvm.Int32Type(), llvm.PointerType(llvm.Int8Type(), 0), llvm.Int64Type()}
+		write = llvm.AddFunction(c.mod, "write", llvm.FunctionType(llvm.Int64Type(), params, false))
+	}
+
+	exit := c.mod.NamedFunction("exit")
+	if exit.IsNil() {
+		exit = llvm.AddFunction(c.mod, "exit", llvm.FunctionType(llvm.VoidType(), []llvm.Type{llvm.Int32Type()}, false))
+	}
+
+	text := "error: " + msg + "\n"
+	c.builder.CreateCall(write, []llvm.Value{
+		llvm.ConstInt(llvm.Int32Type(), 2, false),
+		c.builder.CreateGlobalStringPtr(text, ""),
+		llvm.ConstInt(llvm.Int64Type(), uint64(len(text)), false),
+	}, "")
+	c.builder.CreateCall(exit, []llvm.Value{llvm.ConstInt(llvm.Int32Type(), 1, false)}, "")
+	c.builder.CreateUnreachable()
+}
+
+// failIf ends the program with msg when cond is true
+func (c *CG) failIf(cond llvm.Value, msg string) {
+	fail := llvm.AddBasicBlock(c.function, "fail")
+	ok := llvm.AddBasicBlock(c.function, "ok")
+	c.builder.CreateCondBr(cond, fail, ok)
+
+	c.builder.SetInsertPointAtEnd(fail)
+	c.runtimeError(msg)
+
+	c.builder.SetInsertPointAtEnd(ok)
+}
+
+// checkDivisor makes the program fail when the integer division of left by
+// right is an error. It returns the divisor to use: the smallest integer
+// modulo -1 is computed as modulo 1, which gives the same result without
+// overflowing.
+func (c *CG) checkDivisor(operator string, left, right llvm.Value) llvm.Value {
+	t := right.Type()
+	zero := llvm.ConstInt(t, 0, false)
+	minusOne := llvm.ConstInt(t, ^uint64(0), true)
+	min := llvm.ConstInt(t, 1<<(t.IntTypeWidth()-1), false)
+
+	c.failIf(c.builder.CreateICmp(llvm.IntEQ, right, zero, ""), "division by zero")
+
+	overflow := c.builder.CreateAnd(
+		c.builder.CreateICmp(llvm.IntEQ, left, min, ""),
+		c.builder.CreateICmp(llvm.IntEQ, right, minusOne, ""),
+		"",
+	)
+
+	if operator == "%" {
+		return c.builder.CreateSelect(overflow, llvm.ConstInt(t, 1, false), right, "")
+	}
+
+	c.failIf(overflow, "integer overflow")
+	return right
+}
+
+// checkFloatDivisor makes the program fail when right is zero, like the
+// interpreter does instead of giving an infinity or a NaN. It returns right.
+func (c *CG) checkFloatDivisor(right llvm.Value) llvm.Value {
+	zero := llvm.ConstFloat(right.Type(), 0)
+	c.failIf(c.builder.CreateFCmp(llvm.FloatOEQ, right, zero, ""), "division by zero")
+	return right
+}
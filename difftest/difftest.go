@@ -0,0 +1,176 @@
+package difftest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rumpl/monkey-lang/ast"
+	"github.com/rumpl/monkey-lang/codegen"
+	"github.com/rumpl/monkey-lang/eval"
+	"github.com/rumpl/monkey-lang/lexer"
+	"github.com/rumpl/monkey-lang/object"
+	"github.com/rumpl/monkey-lang/parser"
+)
+
+// Result is the outcome of running a program with one backend. Native
+// binaries can only report their result through the exit status, so values
+// are integers truncated to 8 bits.
+type Result struct {
+	Value string
+	Err   string
+}
+
+func (r Result) String() string {
+	if r.Err != "" {
+		return "error: " + r.Err
+	}
+	return r.Value
+}
+
+// Report is the outcome of running a program with every backend
+type Report struct {
+	File   string
+	Eval   Result
+	Native Result
+}
+
+// compileFailure prefixes the errors of the native backend that happen
+// before the program runs
+const compileFailure = "compilation failed: "
+
+// Mismatch reports whether the backends disagree, either on the value, on
+// the error or because only one of them failed
+func (r *Report) Mismatch() bool {
+	if (r.Eval.Err == "") != (r.Native.Err == "") {
+		return true
+	}
+
+	if r.Eval.Err != "" {
+		return r.Eval.Err != strings.TrimPrefix(r.Native.Err, compileFailure)
+	}
+
+	return r.Eval.Value != r.Native.Value
+}
+
+func (r *Report) String() string {
+	return fmt.Sprintf("%s: eval %s, native %s", r.File, r.Eval, r.Native)
+}
+
+// Files returns the monkey programs found in paths, directories are searched
+// for files with the .monkey extension
+func Files(paths []string) ([]string, error) {
+	var files []string
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(path, "*.monkey"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+
+	return files, nil
+}
+
+// Run runs the program in file with the evaluator and the native backend
+func Run(file string) (*Report, error) {
+	code, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser.New(lexer.New(string(code)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("%s: %s", file, strings.Join(p.Errors(), ", "))
+	}
+
+	native, err := Native(program)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Report{
+		File:   file,
+		Eval:   Eval(program),
+		Native: native,
+	}, nil
+}
+
+// Eval runs program with the tree-walking evaluator. Like the native
+// backend, a main function is the entry point of the program when there is
+// one.
+func Eval(program *ast.Program) Result {
+	env := object.NewEnvironment()
+
+	switch result := eval.Run(program, env).(type) {
+	case nil:
+		return Result{Err: "no result"}
+	case *object.Error:
+		return Result{Err: result.Message}
+	case *object.Integer:
+		return Result{Value: strconv.Itoa(int(uint8(result.Value)))}
+	default:
+		return Result{Value: result.Inspect()}
+	}
+}
+
+// Native compiles program with the LLVM backend and runs the resulting
+// binary. The error is only set when the harness itself fails, problems with
+// the program are reported in the result.
+func Native(program *ast.Program) (Result, error) {
+	dir, err := ioutil.TempDir("", "difftest")
+	if err != nil {
+		return Result{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	c := codegen.New(program)
+	c.Output = filepath.Join(dir, "out")
+
+	if err := c.Codegen(object.NewEnvironment()); err != nil {
+		return Result{Err: compileFailure + err.Error()}, nil
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(c.Output)
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if err == nil {
+		return Result{Value: "0"}, nil
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return Result{}, err
+	}
+
+	if exitErr.ExitCode() < 0 {
+		return Result{Err: exitErr.Error()}, nil
+	}
+
+	// The runtime errors of the program are written to the standard error
+	if msg := strings.TrimSpace(stderr.String()); strings.HasPrefix(msg, "error: ") {
+		return Result{Err: strings.TrimPrefix(msg, "error: ")}, nil
+	}
+
+	return Result{Value: strconv.Itoa(exitErr.ExitCode())}, nil
+}
@@ -0,0 +1,90 @@
+package difftest
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/rumpl/monkey-lang/lexer"
+	"github.com/rumpl/monkey-lang/parser"
+)
+
+// knownMismatches lists the corpus programs the backends are known to
+// disagree on, with the reason why
+var knownMismatches = map[string]string{}
+
+func TestCorpus(t *testing.T) {
+	if _, err := exec.LookPath("cc"); err != nil {
+		t.Skip("cc is needed to link native binaries")
+	}
+
+	files, err := Files([]string{"testdata"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		file := file
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			if reason, ok := knownMismatches[filepath.Base(file)]; ok {
+				t.Skip(reason)
+			}
+
+			report, err := Run(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if report.Mismatch() {
+				t.Error(report)
+			}
+		})
+	}
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Result
+	}{
+		{"5 * 5", Result{Value: "25"}},
+		{"let main = fn() { 300 }; 1", Result{Value: "44"}},
+		{"true", Result{Value: "true"}},
+		{"-true", Result{Err: "unknown operator: -BOOLEAN"}},
+		{"let main = fn() { -true }; 1", Result{Err: "unknown operator: -BOOLEAN"}},
+		{"let a = 1;", Result{Err: "no result"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program := parser.New(lexer.New(tt.input)).ParseProgram()
+			if result := Eval(program); result != tt.expected {
+				t.Errorf("wrong result, expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestMismatch(t *testing.T) {
+	tests := []struct {
+		eval     Result
+		native   Result
+		mismatch bool
+	}{
+		{Result{Value: "1"}, Result{Value: "1"}, false},
+		{Result{Value: "1"}, Result{Value: "2"}, true},
+		{Result{Err: "boom"}, Result{Value: "1"}, true},
+		{Result{Value: "1"}, Result{Err: "signal: segmentation fault"}, true},
+		{Result{Err: "boom"}, Result{Err: "signal: floating point exception"}, true},
+		{Result{Err: "division by zero"}, Result{Err: "division by zero"}, false},
+		{Result{Err: "1:1: boom"}, Result{Err: "compilation failed: 1:1: boom"}, false},
+		{Result{Err: "division by zero"}, Result{Err: "compilation failed: 1:1: boom"}, true},
+	}
+
+	for _, tt := range tests {
+		report := &Report{File: "test.monkey", Eval: tt.eval, Native: tt.native}
+		if report.Mismatch() != tt.mismatch {
+			t.Errorf("%s: expected mismatch to be %t", report, tt.mismatch)
+		}
+	}
+}
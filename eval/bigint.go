@@ -0,0 +1,90 @@
+package eval
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/rumpl/monkey-lang/object"
+)
+
+func isInteger(obj object.Object) bool {
+	return obj.Type() == object.IntegerObj || obj.Type() == object.BigIntObj
+}
+
+func toBigInt(obj object.Object) *big.Int {
+	if i, ok := obj.(*object.Integer); ok {
+		return big.NewInt(i.Value)
+	}
+	return obj.(*object.BigInt).Value
+}
+
+// bigIntResult returns z as an Integer when it fits in one
+func bigIntResult(z *big.Int) object.Object {
+	if z.IsInt64() {
+		return &object.Integer{Value: z.Int64()}
+	}
+	return &object.BigInt{Value: z}
+}
+
+// evalBigIntInfixExpression evaluates an operation on integers when one of
+// them is a BigInt or when the result overflows
+func evalBigIntInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+	l := toBigInt(left)
+	r := toBigInt(right)
+	z := new(big.Int)
+
+	switch operator {
+	case "+":
+		return bigIntResult(z.Add(l, r))
+	case "-":
+		return bigIntResult(z.Sub(l, r))
+	case "*":
+		return bigIntResult(z.Mul(l, r))
+	case "/", "%":
+		if r.Sign() == 0 {
+			return newKindError(object.ArithmeticError, "division by zero")
+		}
+		// Like the operators on Integer, Quo and Rem truncate toward zero
+		if operator == "/" {
+			return bigIntResult(z.Quo(l, r))
+		}
+		return bigIntResult(z.Rem(l, r))
+	case "&":
+		return bigIntResult(z.And(l, r))
+	case "|":
+		return bigIntResult(z.Or(l, r))
+	case "^":
+		return bigIntResult(z.Xor(l, r))
+	case "<<", ">>", ">>>":
+		if r.Sign() < 0 {
+			return newKindError(object.ArithmeticError, "negative shift count: %s", r)
+		}
+		if !r.IsUint64() || r.Uint64() > math.MaxUint32 {
+			return newKindError(object.ArithmeticError, "shift count too large: %s", r)
+		}
+		switch {
+		case operator == "<<":
+			return bigIntResult(z.Lsh(l, uint(r.Uint64())))
+		case operator == ">>" || l.Sign() >= 0:
+			return bigIntResult(z.Rsh(l, uint(r.Uint64())))
+		default:
+			// The unsigned shift reads an Integer as its 64 bits, a negative
+			// BigInt has no such width
+			return newKindError(object.ArithmeticError, "unsigned shift of a negative BigInt: %s", l)
+		}
+	case "<":
+		return nativeBoolToBooleanObject(l.Cmp(r) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(l.Cmp(r) > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(l.Cmp(r) <= 0)
+	case ">=":
+		return nativeBoolToBooleanObject(l.Cmp(r) >= 0)
+	case "==":
+		return nativeBoolToBooleanObject(l.Cmp(r) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(l.Cmp(r) != 0)
+	default:
+		return newKindError(object.TypeError, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
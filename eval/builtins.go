@@ -0,0 +1,272 @@
+package eval
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/rumpl/monkey-lang/object"
+)
+
+// Builtins are the functions defined in every program
+var Builtins = map[string]*object.Builtin{
+	"len": {Fn: func(args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newKindError(object.TypeError, "wrong number of arguments. got=%d, want=1", len(args))
+		}
+
+		switch arg := args[0].(type) {
+		case *object.String:
+			return &object.Integer{Value: int64(len([]rune(arg.Value)))}
+		case *object.Array:
+			return &object.Integer{Value: int64(len(arg.Elements))}
+		case *object.Hash:
+			return &object.Integer{Value: int64(len(arg.Pairs))}
+		default:
+			return newKindError(object.TypeError, "argument to `len` not supported, got %s", args[0].Type())
+		}
+	}},
+	"puts": {Fn: func(args ...object.Object) object.Object {
+		for _, arg := range args {
+			fmt.Println(arg.Inspect())
+		}
+		return Null
+	}},
+	"first": {Fn: func(args ...object.Object) object.Object {
+		arr, err := arrayArgument("first", 1, args)
+		if err != nil {
+			return err
+		}
+		if len(arr.Elements) > 0 {
+			return arr.Elements[0]
+		}
+		return Null
+	}},
+	"last": {Fn: func(args ...object.Object) object.Object {
+		arr, err := arrayArgument("last", 1, args)
+		if err != nil {
+			return err
+		}
+		if length := len(arr.Elements); length > 0 {
+			return arr.Elements[length-1]
+		}
+		return Null
+	}},
+	"rest": {Fn: func(args ...object.Object) object.Object {
+		arr, err := arrayArgument("rest", 1, args)
+		if err != nil {
+			return err
+		}
+		if length := len(arr.Elements); length > 0 {
+			elements := make([]object.Object, length-1)
+			copy(elements, arr.Elements[1:])
+			return &object.Array{Elements: elements}
+		}
+		return Null
+	}},
+	"push": {Fn: func(args ...object.Object) object.Object {
+		arr, err := arrayArgument("push", 2, args)
+		if err != nil {
+			return err
+		}
+		elements := make([]object.Object, len(arr.Elements), len(arr.Elements)+1)
+		copy(elements, arr.Elements)
+		return &object.Array{Elements: append(elements, args[1])}
+	}},
+	"range": {Fn: builtinRange},
+	"int":   {Fn: builtinInt},
+	"float": {Fn: builtinFloat},
+	"error": {Fn: builtinError},
+	"ok": {Fn: func(args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newKindError(object.TypeError, "wrong number of arguments. got=%d, want=1", len(args))
+		}
+		return &object.Result{Ok: true, Value: args[0]}
+	}},
+	"err": {Fn: func(args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newKindError(object.TypeError, "wrong number of arguments. got=%d, want=1", len(args))
+		}
+		return &object.Result{Ok: false, Value: args[0]}
+	}},
+	"is_ok": {Fn: func(args ...object.Object) object.Object {
+		r, err := resultArgument("is_ok", 1, args)
+		if err != nil {
+			return err
+		}
+		return nativeBoolToBooleanObject(r.Ok)
+	}},
+	"is_err": {Fn: func(args ...object.Object) object.Object {
+		r, err := resultArgument("is_err", 1, args)
+		if err != nil {
+			return err
+		}
+		return nativeBoolToBooleanObject(!r.Ok)
+	}},
+	"unwrap": {Fn: func(args ...object.Object) object.Object {
+		r, err := resultArgument("unwrap", 1, args)
+		if err != nil {
+			return err
+		}
+		if r.Ok {
+			return r.Value
+		}
+		// The error of a caught exception is raised again
+		if e, ok := r.Value.(*object.Exception); ok {
+			return e.Error
+		}
+		return newError("unwrap of %s", r.Inspect())
+	}},
+	"unwrap_or": {Fn: func(args ...object.Object) object.Object {
+		r, err := resultArgument("unwrap_or", 2, args)
+		if err != nil {
+			return err
+		}
+		if r.Ok {
+			return r.Value
+		}
+		return args[1]
+	}},
+}
+
+// builtinError makes an exception from error(message) or
+// error(message, kind) for a throw statement, the kind defaults to Error
+func builtinError(args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 2 {
+		return newKindError(object.TypeError, "wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+
+	values := []string{}
+	for _, arg := range args {
+		s, ok := arg.(*object.String)
+		if !ok {
+			return newKindError(object.TypeError, "argument to `error` must be STRING, got %s", arg.Type())
+		}
+		values = append(values, s.Value)
+	}
+
+	err := newError("%s", values[0])
+	if len(values) == 2 {
+		err.Kind = values[1]
+	}
+
+	return &object.Exception{Error: err}
+}
+
+// builtinInt converts a number or a string to an integer, floats are
+// truncated toward zero and large values give a BigInt, an overflow in strict
+// mode
+func builtinInt(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newKindError(object.TypeError, "wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	switch arg := args[0].(type) {
+	case *object.Integer, *object.BigInt:
+		return arg
+	case *object.Float:
+		if math.IsNaN(arg.Value) || math.IsInf(arg.Value, 0) {
+			return newError("cannot convert %s to an integer", arg.Inspect())
+		}
+		if arg.Value >= -(1<<63) && arg.Value < 1<<63 {
+			return &object.Integer{Value: int64(arg.Value)}
+		}
+		i, _ := big.NewFloat(arg.Value).Int(nil)
+		return bigIntResult(i)
+	case *object.String:
+		i, ok := new(big.Int).SetString(strings.TrimSpace(arg.Value), 0)
+		if !ok {
+			return newError("cannot convert %q to an integer", arg.Value)
+		}
+		return bigIntResult(i)
+	default:
+		return newKindError(object.TypeError, "argument to `int` not supported, got %s", arg.Type())
+	}
+}
+
+// builtinFloat converts a number or a string to a float
+func builtinFloat(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newKindError(object.TypeError, "wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	switch arg := args[0].(type) {
+	case *object.Integer, *object.BigInt:
+		return &object.Float{Value: toFloat(arg)}
+	case *object.Float:
+		return arg
+	case *object.String:
+		f, err := strconv.ParseFloat(strings.TrimSpace(arg.Value), 64)
+		if err != nil {
+			return newError("cannot convert %q to a float", arg.Value)
+		}
+		return &object.Float{Value: f}
+	default:
+		return newKindError(object.TypeError, "argument to `float` not supported, got %s", arg.Type())
+	}
+}
+
+// builtinRange implements range(end), range(start, end) and
+// range(start, end, step)
+func builtinRange(args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 3 {
+		return newKindError(object.TypeError, "wrong number of arguments. got=%d, want=1 to 3", len(args))
+	}
+
+	bounds := []int64{}
+	for _, arg := range args {
+		i, ok := arg.(*object.Integer)
+		if !ok {
+			return newKindError(object.TypeError, "argument to `range` must be INTEGER, got %s", arg.Type())
+		}
+		bounds = append(bounds, i.Value)
+	}
+
+	r := &object.Range{Step: 1}
+	switch len(bounds) {
+	case 1:
+		r.End = bounds[0]
+	case 2:
+		r.Start, r.End = bounds[0], bounds[1]
+	case 3:
+		r.Start, r.End, r.Step = bounds[0], bounds[1], bounds[2]
+	}
+
+	if r.Step == 0 {
+		return newError("range step must not be zero")
+	}
+
+	return r
+}
+
+// resultArgument checks that the builtin name got want arguments and that
+// the first one is a result
+func resultArgument(name string, want int, args []object.Object) (*object.Result, *object.Error) {
+	if len(args) != want {
+		return nil, newKindError(object.TypeError, "wrong number of arguments. got=%d, want=%d", len(args), want)
+	}
+
+	r, ok := args[0].(*object.Result)
+	if !ok {
+		return nil, newKindError(object.TypeError, "argument to `%s` must be RESULT, got %s", name, args[0].Type())
+	}
+
+	return r, nil
+}
+
+// arrayArgument checks that the builtin name got want arguments and that the
+// first one is an array
+func arrayArgument(name string, want int, args []object.Object) (*object.Array, *object.Error) {
+	if len(args) != want {
+		return nil, newKindError(object.TypeError, "wrong number of arguments. got=%d, want=%d", len(args), want)
+	}
+
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return nil, newKindError(object.TypeError, "argument to `%s` must be ARRAY, got %s", name, args[0].Type())
+	}
+
+	return arr, nil
+}
@@ -2,6 +2,9 @@ package eval
 
 import (
 	"fmt"
+	"math"
+	"math/big"
+	"runtime/debug"
 
 	"github.com/rumpl/monkey-lang/ast"
 	"github.com/rumpl/monkey-lang/object"
@@ -13,81 +16,192 @@ var (
 	False = &object.Boolean{Value: false}
 )
 
+// Eval evaluates node in env. A Go panic while evaluating is a bug of the
+// interpreter, it is turned into an internal error instead of crashing the
+// program embedding the interpreter.
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	return protect(func() object.Object { return evalNode(node, env) })
+}
+
+// protect calls f and recovers from a panic in it, the panic is returned as
+// an error holding the Go stack trace
+func protect(f func() object.Object) (result object.Object) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = &object.Error{Kind: object.InternalError, Message: fmt.Sprintf("internal error: %v", r), Trace: string(debug.Stack())}
+		}
+	}()
+
+	return f()
+}
+
+func evalNode(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	case *ast.Program:
 		return evalProgram(node, env)
 	case *ast.BlockStatement:
 		return evalBlockStatement(node, env)
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression, env)
+		return evalNode(node.Expression, env)
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
+	case *ast.BigIntegerLiteral:
+		if env.OverflowMode() == object.ErrorOnOverflow {
+			return newKindError(object.ArithmeticError, "integer overflow: %s", node)
+		}
+		return &object.BigInt{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
+	case *ast.StringLiteral:
+		return &object.String{Value: node.Value}
+	case *ast.ArrayLiteral:
+		elements := evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isAbrupt(elements[0]) {
+			return elements[0]
+		}
+		return &object.Array{Elements: elements}
+	case *ast.HashLiteral:
+		return evalHashLiteral(node, env)
+	case *ast.StructLiteral:
+		return evalStructLiteral(node, env)
+	case *ast.IndexExpression:
+		left := evalNode(node.Left, env)
+		if isAbrupt(left) {
+			return left
+		}
+
+		index := evalNode(node.Index, env)
+		if isAbrupt(index) {
+			return index
+		}
+		return evalIndexExpression(left, index)
+	case *ast.FieldExpression:
+		left := evalNode(node.Left, env)
+		if isAbrupt(left) {
+			return left
+		}
+		return evalFieldExpression(left, node.Field.Value)
 	case *ast.PrefixExpression:
-		right := Eval(node.Right, env)
-		if isError(right) {
+		right := evalNode(node.Right, env)
+		if isAbrupt(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return evalPrefixExpression(node.Operator, right, env.OverflowMode())
+	case *ast.PostfixExpression:
+		left := evalNode(node.Left, env)
+		if isAbrupt(left) {
+			return left
+		}
+		return evalPostfixExpression(node.Operator, left)
 	case *ast.InfixExpression:
-		left := Eval(node.Left, env)
-		if isError(left) {
+		if node.Operator == "&&" || node.Operator == "||" {
+			return evalLogicalExpression(node, env)
+		}
+
+		left := evalNode(node.Left, env)
+		if isAbrupt(left) {
 			return left
 		}
 
-		right := Eval(node.Right, env)
-		if isError(right) {
+		right := evalNode(node.Right, env)
+		if isAbrupt(right) {
 			return right
 		}
-		return evalInfixExpression(node.Operator, left, right)
+		return evalInfixExpression(node.Operator, left, right, env.OverflowMode())
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
 	case *ast.ReturnStatement:
-		val := Eval(node.ReturnValue, env)
-		if isError(val) {
+		val := evalNode(node.ReturnValue, env)
+		if isAbrupt(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
 	case *ast.LetStatement:
-		val := Eval(node.Value, env)
-		if isError(val) {
+		for _, name := range ast.PatternNames(node.Name.Pattern) {
+			if env.LocalConstant(name.Value) {
+				return newKindError(object.TypeError, "cannot redeclare constant %s", name.Value)
+			}
+		}
+		val := evalNode(node.Value, env)
+		if isAbrupt(val) {
 			return val
 		}
-		env.Set(node.Name.Value, val)
+		if err := bindPattern(node.Name.Pattern, val, env); err != nil {
+			return err
+		}
+		if node.Constant() {
+			for _, name := range ast.PatternNames(node.Name.Pattern) {
+				env.MakeConstant(name.Value)
+			}
+		}
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
+	case *ast.StructStatement:
+		fields := []string{}
+		for _, f := range node.Fields {
+			fields = append(fields, f.Value)
+		}
+		env.Set(node.Name.Value, &object.StructType{Name: node.Name.Value, Fields: fields, Methods: map[string]*object.Function{}})
+	case *ast.ImplStatement:
+		return evalImplStatement(node, env)
+	case *ast.FunctionStatement:
+		env.Set(node.Name, &object.Function{Parameters: node.Parameters, Env: env, Body: node.Body})
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
 		return &object.Function{Parameters: params, Env: env, Body: body}
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
-		if isError(function) {
+		function := evalNode(node.Function, env)
+		if isAbrupt(function) {
 			return function
 		}
 
-		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
+		args, kwargs, abrupt := evalArguments(node.Arguments, env)
+		if abrupt != nil {
+			return abrupt
+		}
+
+		result := applyFunction(function, args, kwargs)
+		// A builtin like int can make a BigInt out of a float or a string,
+		// which is an overflow like any other in strict mode
+		if n, ok := result.(*object.BigInt); ok && env.OverflowMode() == object.ErrorOnOverflow {
+			result = newKindError(object.ArithmeticError, "integer overflow: %s returned %s", calleeName(node.Function), n.Inspect())
+		}
+		if err, ok := result.(*object.Error); ok {
+			err.Stack = append(err.Stack, fmt.Sprintf("%s (%s)", calleeName(node.Function), node.Function.Pos()))
 		}
-		return applyFunction(function, args)
+		return result
 	case *ast.AssignExpression:
 		return evalAssignment(node, env)
 	case *ast.ForExpression:
 		return evalForLoop(node, env)
+	case *ast.WhileExpression:
+		return evalWhileLoop(node, env)
+	case *ast.ForInExpression:
+		return evalForInLoop(node, env)
+	case *ast.ThrowStatement:
+		return evalThrowStatement(node, env)
+	case *ast.TryExpression:
+		return evalTryExpression(node, env)
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
+	case *ast.BreakStatement:
+		return &object.Break{Label: labelName(node.Label)}
+	case *ast.ContinueStatement:
+		return &object.Continue{Label: labelName(node.Label)}
 	}
 
 	return nil
 }
 
 func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
-	var result []object.Object
+	result := []object.Object{}
 
 	for _, e := range exps {
-		ev := Eval(e, env)
-		if isError(ev) {
+		ev := evalNode(e, env)
+		if isAbrupt(ev) {
 			return []object.Object{ev}
 		}
 		result = append(result, ev)
@@ -96,25 +210,174 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 	return result
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+// keywordArgument is the value passed to a parameter by its name
+type keywordArgument struct {
+	name  string
+	value object.Object
+}
+
+// evalArguments evaluates the arguments of a call, expanding the spread
+// arrays into the positional arguments. The last result is set when an
+// argument errors or returns.
+func evalArguments(exps []ast.Expression, env *object.Environment) ([]object.Object, []keywordArgument, object.Object) {
+	args := []object.Object{}
+	var kwargs []keywordArgument
+
+	for _, e := range exps {
+		switch e := e.(type) {
+		case *ast.SpreadExpression:
+			value := evalNode(e.Value, env)
+			if isAbrupt(value) {
+				return nil, nil, value
+			}
+			arr, ok := value.(*object.Array)
+			if !ok {
+				return nil, nil, newKindError(object.TypeError, "cannot spread %s", value.Type())
+			}
+			args = append(args, arr.Elements...)
+		case *ast.KeywordArgument:
+			value := evalNode(e.Value, env)
+			if isAbrupt(value) {
+				return nil, nil, value
+			}
+			kwargs = append(kwargs, keywordArgument{name: e.Name.Value, value: value})
+		default:
+			value := evalNode(e, env)
+			if isAbrupt(value) {
+				return nil, nil, value
+			}
+			args = append(args, value)
+		}
+	}
+
+	return args, kwargs, nil
+}
+
+func applyFunction(fn object.Object, args []object.Object, kwargs []keywordArgument) object.Object {
+	if builtin, ok := fn.(*object.Builtin); ok {
+		if len(kwargs) > 0 {
+			return newKindError(object.TypeError, "unexpected keyword argument %s", kwargs[0].name)
+		}
+		return builtin.Fn(args...)
+	}
+
 	function, ok := fn.(*object.Function)
 	if !ok {
-		return newError("not a function: %s", fn.Type())
+		return newKindError(object.TypeError, "not a function: %s", fn.Type())
+	}
+
+	extendedEnv, abrupt := extendedFunctionEnv(function, args, kwargs)
+	if abrupt != nil {
+		// A default returning with ? is the result of the call
+		return escapedJump(unwrapReturnValue(abrupt))
 	}
+	evaluated := evalNode(function.Body, extendedEnv)
+	return escapedJump(unwrapReturnValue(evaluated))
+}
 
-	extendedEnv := extendedFunctionEnv(function, args)
-	evaluated := Eval(function.Body, extendedEnv)
-	return unwrapReturnValue(evaluated)
+// calleeName names the function called in a stack trace
+func calleeName(fn ast.Expression) string {
+	switch fn := fn.(type) {
+	case *ast.Identifier:
+		return fn.Value
+	case *ast.FieldExpression:
+		return fn.Field.Value
+	}
+	return "fn"
 }
 
-func extendedFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+// extendedFunctionEnv binds the parameters of fn to the positional args in
+// order, then to the keyword arguments by name. The parameters left get their
+// default, evaluated after the parameters before them are bound, and the rest
+// parameter gets an array of the positional arguments left. The arguments of
+// the parameters declared with a pattern are destructured. The error or the
+// return value a default ends with is returned instead of the environment.
+func extendedFunctionEnv(fn *object.Function, args []object.Object, kwargs []keywordArgument) (*object.Environment, object.Object) {
 	env := object.NewEnclosedEnvironment(fn.Env)
 
+	positional, required, rest := len(fn.Parameters), 0, false
+	for _, param := range fn.Parameters {
+		switch {
+		case param.Rest:
+			positional--
+			rest = true
+		case param.Default == nil:
+			required++
+		}
+	}
+
+	if len(args) > positional && !rest {
+		return nil, arityError(len(args)+len(kwargs), required, positional, rest)
+	}
+
+	values := make([]object.Object, len(fn.Parameters))
+	for i := 0; i < len(args) && i < positional; i++ {
+		values[i] = args[i]
+	}
+
+	for _, kw := range kwargs {
+		i := parameterIndex(fn, kw.name)
+		if i < 0 {
+			return nil, newKindError(object.TypeError, "unexpected keyword argument %s", kw.name)
+		}
+		if values[i] != nil {
+			return nil, newKindError(object.TypeError, "multiple values for parameter %s", kw.name)
+		}
+		values[i] = kw.value
+	}
+
 	for i, param := range fn.Parameters {
-		env.Set(param.Value, args[i])
+		value := values[i]
+		switch {
+		case param.Rest:
+			others := []object.Object{}
+			if len(args) > positional {
+				others = append(others, args[positional:]...)
+			}
+			value = &object.Array{Elements: others}
+		case value != nil:
+		case param.Default != nil:
+			value = evalNode(param.Default, env)
+			if isAbrupt(value) {
+				return nil, value
+			}
+		case len(kwargs) > 0:
+			return nil, newKindError(object.TypeError, "missing argument for parameter %s", param.Pattern)
+		default:
+			return nil, arityError(len(args), required, positional, rest)
+		}
+
+		if err := bindPattern(param.Pattern, value, env); err != nil {
+			return nil, err
+		}
 	}
 
-	return env
+	return env, nil
+}
+
+// parameterIndex returns the index of the parameter of fn a keyword argument
+// can name, -1 when there is none
+func parameterIndex(fn *object.Function, name string) int {
+	for i, param := range fn.Parameters {
+		if id := param.Variable(); id != nil && id.Value == name && !param.Rest {
+			return i
+		}
+	}
+	return -1
+}
+
+// arityError reports a call passing got arguments to a function taking
+// between required and positional of them, or more with a rest parameter
+func arityError(got, required, positional int, rest bool) *object.Error {
+	want := fmt.Sprint(required)
+	switch {
+	case rest:
+		want = fmt.Sprintf("at least %d", required)
+	case required != positional:
+		want = fmt.Sprintf("%d to %d", required, positional)
+	}
+
+	return newKindError(object.TypeError, "wrong number of arguments. got=%d, want=%s", got, want)
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
@@ -132,17 +395,60 @@ func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	return False
 }
 
+// Run evaluates program and then calls its main function when it has one,
+// like the compiled program does
+func Run(program *ast.Program, env *object.Environment) object.Object {
+	return protect(func() object.Object { return run(program, env) })
+}
+
+func run(program *ast.Program, env *object.Environment) object.Object {
+	result := evalNode(program, env)
+	if isError(result) {
+		return result
+	}
+
+	main, ok := env.Get("main")
+	if !ok {
+		return result
+	}
+
+	fn, ok := main.(*object.Function)
+	if !ok {
+		return result
+	}
+
+	if len(fn.Parameters) != 0 {
+		return newError("main must not have parameters")
+	}
+
+	return applyFunction(fn, []object.Object{}, nil)
+}
+
+// hoistFunctions binds the functions declared with fn statements before the
+// statements run, so they can be called before their declaration
+func hoistFunctions(stmts []ast.Statement, env *object.Environment) {
+	for _, stmt := range stmts {
+		if fs, ok := stmt.(*ast.FunctionStatement); ok {
+			evalNode(fs, env)
+		}
+	}
+}
+
 func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
+	hoistFunctions(program.Statements, env)
+
 	for _, stmt := range program.Statements {
-		result = Eval(stmt, env)
+		result = evalNode(stmt, env)
 
 		switch result := result.(type) {
 		case *object.ReturnValue:
 			return result.Value
 		case *object.Error:
 			return result
+		case *object.Break, *object.Continue:
+			return escapedJump(result)
 		}
 	}
 
@@ -152,11 +458,13 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 
+	hoistFunctions(block.Statements, env)
+
 	for _, stmt := range block.Statements {
-		result = Eval(stmt, env)
+		result = evalNode(stmt, env)
 		if result != nil {
 			rt := result.Type()
-			if rt == object.ReturnValueObj || rt == object.ErrorObj {
+			if rt == object.ReturnValueObj || rt == object.ErrorObj || rt == object.BreakObj || rt == object.ContinueObj {
 				return result
 			}
 		}
@@ -165,15 +473,37 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	return result
 }
 
-func evalPrefixExpression(operator string, right object.Object) object.Object {
+func evalPrefixExpression(operator string, right object.Object, mode object.OverflowMode) object.Object {
 	switch operator {
 	case "!":
 		return evalBangOperatorExpression(right)
 	case "-":
-		return evalMinusPrefixOperatorExpression(right)
+		return evalMinusPrefixOperatorExpression(right, mode)
+	case "~":
+		switch right := right.(type) {
+		case *object.Integer:
+			return &object.Integer{Value: ^right.Value}
+		case *object.BigInt:
+			return bigIntResult(new(big.Int).Not(right.Value))
+		}
+		return newKindError(object.TypeError, "unknown operator: ~%s", right.Type())
 	default:
-		return newError("unknown operator: %s%s", operator, right.Type())
+		return newKindError(object.TypeError, "unknown operator: %s%s", operator, right.Type())
+	}
+}
+
+// evalPostfixExpression evaluates "r?", the value of ok(v) is v and err(e)
+// returns itself from the enclosing function
+func evalPostfixExpression(operator string, left object.Object) object.Object {
+	result, ok := left.(*object.Result)
+	if operator != "?" || !ok {
+		return newKindError(object.TypeError, "unknown operator: %s%s", left.Type(), operator)
 	}
+
+	if result.Ok {
+		return result.Value
+	}
+	return &object.ReturnValue{Value: result}
 }
 
 func evalBangOperatorExpression(right object.Object) object.Object {
@@ -189,33 +519,65 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 	}
 }
 
-func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.IntegerObj {
-		return newError("unknown operator: -%s", right.Type())
+func evalMinusPrefixOperatorExpression(right object.Object, mode object.OverflowMode) object.Object {
+	switch right := right.(type) {
+	case *object.Integer:
+		if right.Value == math.MinInt64 {
+			if mode == object.ErrorOnOverflow {
+				return newKindError(object.ArithmeticError, "integer overflow: -(%d)", right.Value)
+			}
+			return bigIntResult(new(big.Int).Neg(big.NewInt(right.Value)))
+		}
+		return &object.Integer{Value: -1 * right.Value}
+	case *object.BigInt:
+		return bigIntResult(new(big.Int).Neg(right.Value))
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
+		return newKindError(object.TypeError, "unknown operator: -%s", right.Type())
 	}
-	integer := right.(*object.Integer)
-	return &object.Integer{Value: -1 * integer.Value}
 }
 
-func evalInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+func evalInfixExpression(operator string, left object.Object, right object.Object, mode object.OverflowMode) object.Object {
+	if result, ok := evalOperatorMethod(operator, left, right); ok {
+		return result
+	}
+
 	switch {
 	case left.Type() == object.IntegerObj && right.Type() == object.IntegerObj:
-		return evalIntegerInfixExpression(operator, left, right)
+		return evalIntegerInfixExpression(operator, left, right, mode)
+	case isInteger(left) && isInteger(right):
+		return evalBigIntInfixExpression(operator, left, right)
+	case isNumber(left) && isNumber(right):
+		// An integer mixed with a float is promoted to a float
+		return evalFloatInfixExpression(operator, left, right)
+	case left.Type() == object.StringObj && right.Type() == object.StringObj:
+		return evalStringInfixExpression(operator, left, right)
+	case left.Type() == object.StructObj && right.Type() == object.StructObj && (operator == "==" || operator == "!="):
+		equal := structsEqual(left.(*object.Struct), right.(*object.Struct), mode)
+		return nativeBoolToBooleanObject(equal == (operator == "=="))
 	case operator == "==":
 		return nativeBoolToBooleanObject(left == right)
 	case operator == "!=":
 		return nativeBoolToBooleanObject(left != right)
 	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+		return newKindError(object.TypeError, "type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newKindError(object.TypeError, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
-func evalIntegerInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+func evalIntegerInfixExpression(operator string, left object.Object, right object.Object, mode object.OverflowMode) object.Object {
 	li := left.(*object.Integer)
 	ri := right.(*object.Integer)
 
+	if object.Overflows(operator, li.Value, ri.Value) {
+		if mode == object.ErrorOnOverflow {
+			return newKindError(object.ArithmeticError, "integer overflow: %d %s %d", li.Value, operator, ri.Value)
+		}
+		return evalBigIntInfixExpression(operator, left, right)
+	}
+
 	switch operator {
 	case "+":
 		return &object.Integer{Value: li.Value + ri.Value}
@@ -224,68 +586,580 @@ func evalIntegerInfixExpression(operator string, left object.Object, right objec
 	case "*":
 		return &object.Integer{Value: li.Value * ri.Value}
 	case "/":
+		if ri.Value == 0 {
+			return newKindError(object.ArithmeticError, "division by zero")
+		}
 		return &object.Integer{Value: li.Value / ri.Value}
+	case "%":
+		if ri.Value == 0 {
+			return newKindError(object.ArithmeticError, "division by zero")
+		}
+		return &object.Integer{Value: li.Value % ri.Value}
+	case "&":
+		return &object.Integer{Value: li.Value & ri.Value}
+	case "|":
+		return &object.Integer{Value: li.Value | ri.Value}
+	case "^":
+		return &object.Integer{Value: li.Value ^ ri.Value}
+	case "<<", ">>", ">>>":
+		if ri.Value < 0 {
+			return newKindError(object.ArithmeticError, "negative shift count: %d", ri.Value)
+		}
+		return evalShift(operator, li.Value, uint64(ri.Value))
 	case "<":
 		return nativeBoolToBooleanObject(li.Value < ri.Value)
 	case ">":
 		return nativeBoolToBooleanObject(li.Value > ri.Value)
+	case "<=":
+		return nativeBoolToBooleanObject(li.Value <= ri.Value)
+	case ">=":
+		return nativeBoolToBooleanObject(li.Value >= ri.Value)
 	case "!=":
 		return nativeBoolToBooleanObject(li.Value != ri.Value)
 	case "==":
 		return nativeBoolToBooleanObject(li.Value == ri.Value)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newKindError(object.TypeError, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// evalLogicalExpression evaluates && and ||, the right operand is only
+// evaluated when the left one doesn't decide the result
+func evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := evalNode(node.Left, env)
+	if isAbrupt(left) {
+		return left
+	}
+
+	if isTruthy(left) == (node.Operator == "||") {
+		return nativeBoolToBooleanObject(isTruthy(left))
+	}
+
+	right := evalNode(node.Right, env)
+	if isAbrupt(right) {
+		return right
+	}
+
+	return nativeBoolToBooleanObject(isTruthy(right))
+}
+
+func evalStringInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+	ls := left.(*object.String).Value
+	rs := right.(*object.String).Value
+
+	switch operator {
+	case "+":
+		return &object.String{Value: ls + rs}
+	case "==":
+		return nativeBoolToBooleanObject(ls == rs)
+	case "!=":
+		return nativeBoolToBooleanObject(ls != rs)
+	default:
+		return newKindError(object.TypeError, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	hash := object.NewHash()
+
+	for _, pair := range node.Pairs {
+		key := evalNode(pair.Key, env)
+		if isAbrupt(key) {
+			return key
+		}
+
+		if _, ok := key.(object.Hashable); !ok {
+			return newKindError(object.TypeError, "unusable as hash key: %s", key.Type())
+		}
+
+		value := evalNode(pair.Value, env)
+		if isAbrupt(value) {
+			return value
+		}
+
+		hash.Set(key, value)
+	}
+
+	return hash
+}
+
+func evalIndexExpression(left object.Object, index object.Object) object.Object {
+	switch {
+	case left.Type() == object.ArrayObj && index.Type() == object.IntegerObj:
+		elements := left.(*object.Array).Elements
+		i := index.(*object.Integer).Value
+		if i < 0 || i >= int64(len(elements)) {
+			return Null
+		}
+		return elements[i]
+	case left.Type() == object.HashObj:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newKindError(object.TypeError, "unusable as hash key: %s", index.Type())
+		}
+		if value, ok := left.(*object.Hash).Get(key); ok {
+			return value
+		}
+		return Null
+	case left.Type() == object.ExceptionObj && index.Type() == object.StringObj:
+		return evalExceptionField(left.(*object.Exception), index.(*object.String).Value)
+	default:
+		return newKindError(object.TypeError, "index operator not supported: %s", left.Type())
+	}
+}
+
+// evalFieldExpression gives the field of a value, the field of a hash is
+// the value of its key named like it
+func evalFieldExpression(left object.Object, field string) object.Object {
+	switch left := left.(type) {
+	case *object.Hash:
+		if value, ok := left.Get(&object.String{Value: field}); ok {
+			return value
+		}
+		return Null
+	case *object.Struct:
+		return evalStructField(left, field)
+	case *object.Exception:
+		return evalExceptionField(left, field)
+	}
+
+	return newKindError(object.TypeError, "field access not supported: %s", left.Type())
+}
+
+// evalExceptionField gives the fields of a caught error: its message, its
+// kind and the calls it went through
+func evalExceptionField(e *object.Exception, field string) object.Object {
+	switch field {
+	case "message":
+		return &object.String{Value: e.Error.Message}
+	case "kind":
+		return &object.String{Value: e.Error.Kind}
+	case "trace":
+		trace := &object.Array{Elements: []object.Object{}}
+		for _, call := range e.Error.Stack {
+			trace.Elements = append(trace.Elements, &object.String{Value: call})
+		}
+		return trace
+	}
+	return Null
+}
+
+func evalFloatInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+	lf := toFloat(left)
+	rf := toFloat(right)
+
+	switch operator {
+	case "+":
+		return &object.Float{Value: lf + rf}
+	case "-":
+		return &object.Float{Value: lf - rf}
+	case "*":
+		return &object.Float{Value: lf * rf}
+	case "/", "%":
+		// Like on integers, dividing by zero is an error rather than an
+		// infinity or a NaN
+		if rf == 0 {
+			return newKindError(object.ArithmeticError, "division by zero")
+		}
+		if operator == "/" {
+			return &object.Float{Value: lf / rf}
+		}
+		return &object.Float{Value: math.Mod(lf, rf)}
+	case "<":
+		return nativeBoolToBooleanObject(lf < rf)
+	case ">":
+		return nativeBoolToBooleanObject(lf > rf)
+	case "<=":
+		return nativeBoolToBooleanObject(lf <= rf)
+	case ">=":
+		return nativeBoolToBooleanObject(lf >= rf)
+	case "!=":
+		return nativeBoolToBooleanObject(lf != rf)
+	case "==":
+		return nativeBoolToBooleanObject(lf == rf)
+	default:
+		return newKindError(object.TypeError, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func isNumber(obj object.Object) bool {
+	return isInteger(obj) || obj.Type() == object.FloatObj
+}
+
+// toFloat returns the value of an integer or a float as a float
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.BigInt:
+		f, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return f
+	}
+	return obj.(*object.Float).Value
+}
+
+// evalShift shifts the 64 bits of value, >> keeps the sign and >>> fills
+// with zeros
+func evalShift(operator string, value int64, count uint64) object.Object {
+	switch operator {
+	case "<<":
+		return &object.Integer{Value: value << count}
+	case ">>":
+		return &object.Integer{Value: value >> count}
+	default:
+		return &object.Integer{Value: int64(uint64(value) >> count)}
 	}
 }
 
 func evalIfExpression(obj *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(obj.Condition, env)
-	if isError(condition) {
+	condition := evalNode(obj.Condition, env)
+	if isAbrupt(condition) {
 		return condition
 	}
 
 	if isTruthy(condition) {
-		return Eval(obj.Consequence, env)
+		return evalNode(obj.Consequence, object.NewEnclosedEnvironment(env))
 	} else if obj.Alternative != nil {
-		return Eval(obj.Alternative, env)
+		return evalNode(obj.Alternative, object.NewEnclosedEnvironment(env))
 	}
 	return Null
 }
 
 func evalIdentifier(id *ast.Identifier, env *object.Environment) object.Object {
-	val, ok := env.Get(id.Value)
-	if !ok {
-		return newError("identifier not found: " + id.Value)
+	if val, ok := env.Get(id.Value); ok {
+		return val
+	}
+
+	if builtin, ok := Builtins[id.Value]; ok {
+		return builtin
 	}
 
-	return val
+	return newKindError(object.NameError, "identifier not found: %s", id.Value)
 }
 
 func evalAssignment(id *ast.AssignExpression, env *object.Environment) object.Object {
-	_, ok := env.Get(id.Left.Value)
-	if !ok {
-		return newError("identifier not found: " + id.Left.Value)
+	switch left := id.Left.(type) {
+	case *ast.IndexExpression:
+		return evalIndexAssignment(id, left, env)
+	case *ast.FieldExpression:
+		return evalFieldAssignment(id, left, env)
+	}
+
+	name := id.Left.(*ast.Identifier).Value
+	if _, ok := env.Get(name); !ok {
+		return newKindError(object.NameError, "identifier not found: %s", name)
+	}
+	if env.Constant(name) {
+		return newKindError(object.TypeError, "cannot assign to constant %s", name)
+	}
+
+	a := evalNode(id.Expression, env)
+	if isAbrupt(a) {
+		return a
+	}
+
+	// The variable is updated where it is defined, the blocks and closures
+	// assigning to it see the same variable
+	env.Assign(name, a)
+
+	return a
+}
+
+// evalIndexAssignment stores a value in an element of an array or a hash.
+// The array or hash is updated in place, every variable holding it sees the
+// change. The array or hash and the index are evaluated once, before the
+// value.
+func evalIndexAssignment(ae *ast.AssignExpression, target *ast.IndexExpression, env *object.Environment) object.Object {
+	left := evalNode(target.Left, env)
+	if isAbrupt(left) {
+		return left
+	}
+
+	index := evalNode(target.Index, env)
+	if isAbrupt(index) {
+		return index
+	}
+
+	value := evalAssignedValue(ae, env, func() object.Object { return evalIndexExpression(left, index) })
+	if isAbrupt(value) {
+		return value
+	}
+
+	switch {
+	case left.Type() == object.ArrayObj && index.Type() == object.IntegerObj:
+		elements := left.(*object.Array).Elements
+		i := index.(*object.Integer).Value
+		if i < 0 || i >= int64(len(elements)) {
+			return newError("index %d out of range for an array of length %d", i, len(elements))
+		}
+		elements[i] = value
+	case left.Type() == object.HashObj:
+		if _, ok := index.(object.Hashable); !ok {
+			return newKindError(object.TypeError, "unusable as hash key: %s", index.Type())
+		}
+		left.(*object.Hash).Set(index, value)
+	default:
+		return newKindError(object.TypeError, "index assignment not supported: %s[%s]", left.Type(), index.Type())
+	}
+
+	return value
+}
+
+// evalFieldAssignment stores a value in a field, updating the hash or the
+// struct holding it in place
+func evalFieldAssignment(ae *ast.AssignExpression, target *ast.FieldExpression, env *object.Environment) object.Object {
+	left := evalNode(target.Left, env)
+	if isAbrupt(left) {
+		return left
+	}
+
+	value := evalAssignedValue(ae, env, func() object.Object { return evalFieldExpression(left, target.Field.Value) })
+	if isAbrupt(value) {
+		return value
+	}
+
+	switch left := left.(type) {
+	case *object.Hash:
+		left.Set(&object.String{Value: target.Field.Value}, value)
+	case *object.Struct:
+		if !left.StructType.HasField(target.Field.Value) {
+			return newKindError(object.TypeError, "struct %s has no field %s", left.StructType.Name, target.Field.Value)
+		}
+		left.Fields[target.Field.Value] = value
+	default:
+		return newKindError(object.TypeError, "field assignment not supported: %s", left.Type())
+	}
+
+	return value
+}
+
+// evalAssignedValue evaluates the value assigned to an element or a field,
+// applying the operator of a compound assignment to the current value
+func evalAssignedValue(ae *ast.AssignExpression, env *object.Environment, current func() object.Object) object.Object {
+	if ae.Operator == "" {
+		return evalNode(ae.Expression, env)
+	}
+
+	left := current()
+	if isAbrupt(left) {
+		return left
+	}
+
+	right := evalNode(ae.Expression.(*ast.InfixExpression).Right, env)
+	if isAbrupt(right) {
+		return right
+	}
+
+	return evalInfixExpression(ae.Operator, left, right, env.OverflowMode())
+}
+
+func evalForLoop(fl *ast.ForExpression, outer *object.Environment) object.Object {
+	// The variable declared by the loop is only visible in the loop, the
+	// variables of the body are local to each iteration
+	env := object.NewEnclosedEnvironment(outer)
+	if init := evalNode(fl.Initial, env); isAbrupt(init) {
+		return init
+	}
+
+	var res object.Object = Null
+
+	for {
+		condition := evalNode(fl.StopCondition, env)
+		if isAbrupt(condition) {
+			return condition
+		}
+
+		if !isTruthy(condition) {
+			return res
+		}
+
+		result := evalNode(fl.Statements, object.NewEnclosedEnvironment(env))
+		if exit, done := loopIteration(fl.Label, result); done {
+			if exit == nil {
+				return res
+			}
+			return exit
+		} else if !isJump(result) {
+			res = result
+		}
+
+		if inc := evalNode(fl.Increment, env); isAbrupt(inc) {
+			return inc
+		}
 	}
+}
+
+func evalWhileLoop(wl *ast.WhileExpression, env *object.Environment) object.Object {
+	var res object.Object = Null
+
+	for {
+		condition := evalNode(wl.Condition, env)
+		if isAbrupt(condition) {
+			return condition
+		}
 
-	a := Eval(id.Expression, env)
+		if !isTruthy(condition) {
+			return res
+		}
 
-	return env.Set(id.Left.Value, a)
+		result := evalNode(wl.Body, object.NewEnclosedEnvironment(env))
+		if exit, done := loopIteration(wl.Label, result); done {
+			if exit == nil {
+				return res
+			}
+			return exit
+		} else if !isJump(result) {
+			res = result
+		}
+	}
 }
 
-func evalForLoop(fl *ast.ForExpression, env *object.Environment) object.Object {
-	Eval(fl.Initial, env)
+func evalForInLoop(fl *ast.ForInExpression, env *object.Environment) object.Object {
+	obj := evalNode(fl.Iterable, env)
+	if isAbrupt(obj) {
+		return obj
+	}
+
+	iterable, ok := obj.(object.Iterable)
+	if !ok {
+		return newKindError(object.TypeError, "not iterable: %s", obj.Type())
+	}
 
 	var res object.Object = Null
 
+	it := iterable.Iterate()
 	for {
-		if !isTruthy(Eval(fl.StopCondition, env)) {
+		key, value, ok := it.Next()
+		if !ok {
 			return res
 		}
 
-		res = Eval(fl.Statements, env)
+		// Every iteration gets its own variables, the closures created in
+		// the body capture the values of their iteration
+		iterEnv := object.NewEnclosedEnvironment(env)
+		if len(fl.Names) == 2 {
+			iterEnv.Set(fl.Names[0].Value, key)
+			iterEnv.Set(fl.Names[1].Value, value)
+		} else if _, ok := obj.(*object.Hash); ok {
+			iterEnv.Set(fl.Names[0].Value, key)
+		} else {
+			iterEnv.Set(fl.Names[0].Value, value)
+		}
+
+		result := evalNode(fl.Body, iterEnv)
+		if exit, done := loopIteration(fl.Label, result); done {
+			if exit == nil {
+				return res
+			}
+			return exit
+		} else if !isJump(result) {
+			res = result
+		}
+	}
+}
+
+// evalThrowStatement raises the value of the statement. An exception is
+// raised again as it was caught, any other value becomes the message of a
+// new error.
+func evalThrowStatement(ts *ast.ThrowStatement, env *object.Environment) object.Object {
+	val := evalNode(ts.Value, env)
+	if isAbrupt(val) {
+		return val
+	}
+
+	switch val := val.(type) {
+	case *object.Exception:
+		return val.Error
+	case *object.String:
+		return newError("%s", val.Value)
+	default:
+		return newError("%s", val.Inspect())
+	}
+}
+
+// evalTryExpression evaluates the body of the try and the catch clause when
+// it fails. The finally clause runs after them whatever happens, its result
+// is only kept when it leaves the try with a return, an error or a jump.
+func evalTryExpression(te *ast.TryExpression, env *object.Environment) object.Object {
+	result := evalNode(te.Body, object.NewEnclosedEnvironment(env))
+
+	if err, ok := result.(*object.Error); ok && te.Catch != nil {
+		catchEnv := object.NewEnclosedEnvironment(env)
+		if te.Param != nil {
+			catchEnv.Set(te.Param.Value, &object.Exception{Error: err})
+		}
+		result = evalNode(te.Catch, catchEnv)
+	}
+
+	if te.Finally != nil {
+		switch final := evalNode(te.Finally, object.NewEnclosedEnvironment(env)).(type) {
+		case *object.ReturnValue, *object.Error, *object.Break, *object.Continue:
+			return final
+		}
+	}
+
+	return result
+}
+
+// loopIteration looks at the result of an iteration of the loop labeled
+// label and tells whether the loop is done. A loop ends when it is broken
+// out of, the object to pass up is nil in that case. Returns, errors and
+// jumps to an outer loop end it too and are passed up.
+func loopIteration(label *ast.Identifier, result object.Object) (object.Object, bool) {
+	switch result := result.(type) {
+	case *object.Break:
+		if targets(label, result.Label) {
+			return nil, true
+		}
+		return result, true
+	case *object.Continue:
+		if targets(label, result.Label) {
+			return nil, false
+		}
+		return result, true
+	case *object.ReturnValue, *object.Error:
+		return result, true
+	}
+
+	return nil, false
+}
+
+// targets tells if a break or continue to the label name is meant for the
+// loop labeled label, an empty name is meant for the innermost loop
+func targets(label *ast.Identifier, name string) bool {
+	return name == "" || (label != nil && label.Value == name)
+}
+
+func isJump(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Break, *object.Continue:
+		return true
+	}
+	return false
+}
 
-		Eval(fl.Increment, env)
+// escapedJump turns a break or continue that left every loop into an error
+func escapedJump(obj object.Object) object.Object {
+	switch obj := obj.(type) {
+	case *object.Break:
+		if obj.Label != "" {
+			return newError("break to unknown label %s", obj.Label)
+		}
+		return newError("break outside of a loop")
+	case *object.Continue:
+		if obj.Label != "" {
+			return newError("continue to unknown label %s", obj.Label)
+		}
+		return newError("continue outside of a loop")
 	}
+	return obj
+}
+
+func labelName(label *ast.Identifier) string {
+	if label == nil {
+		return ""
+	}
+	return label.Value
 }
 
 func isTruthy(obj object.Object) bool {
@@ -308,6 +1182,20 @@ func isError(obj object.Object) bool {
 	return false
 }
 
+// isAbrupt tells if obj cuts the evaluation of an expression short, it is an
+// error, the early return of the ? operator or a break or continue leaving
+// the expression for its loop
+func isAbrupt(obj object.Object) bool {
+	if obj != nil {
+		return obj.Type() == object.ErrorObj || obj.Type() == object.ReturnValueObj || isJump(obj)
+	}
+	return false
+}
+
 func newError(format string, a ...interface{}) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, a...)}
+	return newKindError(object.GenericError, format, a...)
+}
+
+func newKindError(kind string, format string, a ...interface{}) *object.Error {
+	return &object.Error{Kind: kind, Message: fmt.Sprintf(format, a...)}
 }
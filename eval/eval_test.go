@@ -1,8 +1,10 @@
 package eval
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/rumpl/monkey-lang/ast"
 	"github.com/rumpl/monkey-lang/lexer"
 	"github.com/rumpl/monkey-lang/object"
 	"github.com/rumpl/monkey-lang/parser"
@@ -179,6 +181,34 @@ return 1`, "unknown operator: BOOLEAN + BOOLEAN",
 			"foobar",
 			"identifier not found: foobar",
 		},
+		{
+			"1 / 0",
+			"division by zero",
+		},
+		{
+			"let f = fn(n) { 10 % n }; f(0)",
+			"division by zero",
+		},
+		{
+			"(1 << 64) / 0",
+			"division by zero",
+		},
+		{
+			"(1 << 64) % (1 - 1)",
+			"division by zero",
+		},
+		{
+			"1.0 / 0",
+			"division by zero",
+		},
+		{
+			"1.0 % 0.0",
+			"division by zero",
+		},
+		{
+			"let f = fn(x) { 2 / x }; f(-0.0)",
+			"division by zero",
+		},
 	}
 
 	for _, tt := range tests {
@@ -258,12 +288,69 @@ func TestFunctionApplication(t *testing.T) {
 	}
 }
 
+func TestFunctionStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"fn double(x) { x * 2 } double(4);", 8},
+		{"let r = double(4); fn double(x) { x * 2 } r;", 8},
+		{"fn fact(n) { if (n < 2) { return 1; } n * fact(n - 1) } fact(5);", 120},
+		{"fn even(n) { if (n == 0) { true } else { odd(n - 1) } } fn odd(n) { if (n == 0) { false } else { even(n - 1) } } if (odd(7)) { 1 } else { 0 }", 1},
+		{"let f = fn() { return g(); fn g() { 3 } }; f();", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			testIntegerObject(t, testEval(tt.input), tt.expected)
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"fn main() { test() } fn test() { 12 * 12 }", int64(144)},
+		{"let a = 5; a;", int64(5)},
+		{"let main = fn() { 7 };", int64(7)},
+		{"fn main(x) { x }", "main must not have parameters"},
+		{"1 + true; fn main() { 1 }", "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program := parser.New(lexer.New(tt.input)).ParseProgram()
+			evaluated := Run(program, object.NewEnvironment())
+
+			switch expected := tt.expected.(type) {
+			case int64:
+				testIntegerObject(t, evaluated, expected)
+			case string:
+				errObj, ok := evaluated.(*object.Error)
+				if !ok {
+					t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+				}
+				if errObj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+				}
+			}
+		})
+	}
+}
+
 func TestAssignment(t *testing.T) {
 	tests := []struct {
 		input    string
 		expected int64
 	}{
 		{"let a = 1; a = a + 1; a;", 2},
+		{"let a = 1; a += 4; a -= 2; a *= 5; a /= 3; a %= 4; a;", 1},
+		{"let a = 1; a++; a++; a--; a;", 2},
+		{"let n = 0; for (let i = 0; i < 4; i++) { n += i }; n;", 6},
+		{"let a = 1; let f = fn() { a += 10 }; f(); a;", 11},
+		{"let a = 2; a += a *= 3;", 8},
 	}
 
 	for _, tt := range tests {
@@ -273,6 +360,142 @@ func TestAssignment(t *testing.T) {
 	}
 }
 
+func TestIndexAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = [1, 2, 3]; a[0] = 10; a", "[10, 2, 3]"},
+		{"let a = [1, 2]; let b = a; a[1] = 5; b", "[1, 5]"},
+		{"let a = [1, 2]; let b = push(a, 3); a[0] = 0; b", "[1, 2, 3]"},
+		{"let g = [[0, 0], [0, 0]]; g[1][0] = 7; g", "[[0, 0], [7, 0]]"},
+		{`let h = {"k": 1}; h["k"] = 2; h["n"] = 3; h`, "{k: 2, n: 3}"},
+		{`let h = {}; h.name = "ann"; [h.name, h["name"], h.age]`, "[ann, ann, null]"},
+		{`let h = {"n": 1}; h.n += 4; h.n`, "5"},
+		{"let n = 0; let a = [1, 2]; let f = fn() { n++; 1 }; a[f()] += 5; [a, n]", "[[1, 7], 1]"},
+		{"const a = [1]; a[0] = 2; a", "[2]"},
+		{"let f = fn(a) { a[0] = 1 }; let a = [0]; f(a); a", "[1]"},
+		{"let a = [1]; a[1] = 2;", "ERROR: index 1 out of range for an array of length 1"},
+		{`let s = "ab"; s[0] = "c";`, "ERROR: index assignment not supported: STRING[INTEGER]"},
+		{"let h = {}; h[fn() {}] = 1;", "ERROR: unusable as hash key: FUNCTION"},
+		{"let n = 1; n.x = 1;", "ERROR: field assignment not supported: INTEGER"},
+		{"let n = 1; n.x", "ERROR: field access not supported: INTEGER"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, evaluated.Inspect())
+			}
+		})
+	}
+}
+
+func TestStructs(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"struct Point { x, y } Point { x: 1, y: 2 }", "Point { x: 1, y: 2 }"},
+		{"struct Point { x, y } Point", "struct Point { x, y }"},
+		{"struct Point { x, y } let p = Point { y: 2, x: 1 }; p.x * 10 + p.y", "12"},
+		{"struct Point { x, y } let p = Point { x: 1, y: 2 }; let q = p; p.x += 5; q.x", "6"},
+		{"struct Point { x, y } Point { x: 1, y: [2] } == Point { x: 1, y: [2] }", "false"},
+		{"struct Point { x, y } Point { x: 1, y: 2 } == Point { y: 2, x: 1 }", "true"},
+		{"struct Point { x, y } Point { x: 1, y: 2 } != Point { x: 1, y: 3 }", "true"},
+		{"struct A { v } struct B { v } A { v: 1 } == B { v: 1 }", "false"},
+		{"struct A { v } A { v: A { v: 1 } } == A { v: A { v: 1 } }", "true"},
+		{"struct Point { x, y } Point { x: 1 }", "ERROR: missing field y of Point"},
+		{"struct Point { x, y } Point { x: 1, y: 2, z: 3 }", "ERROR: struct Point has no field z"},
+		{"struct Point { x, y } Point { x: 1, y: 2 }.z", "ERROR: struct Point has no field z"},
+		{"struct Point { x, y } let p = Point { x: 1, y: 2 }; p.z = 3;", "ERROR: struct Point has no field z"},
+		{"let Point = 1; Point { x: 1 }", "ERROR: not a struct type: Point"},
+		{"Point { x: 1 }", "ERROR: identifier not found: Point"},
+		{"struct Point { x, y } Point { x: 1, y: 2 } + 1", "ERROR: type mismatch: STRUCT + INTEGER"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, evaluated.Inspect())
+			}
+		})
+	}
+}
+
+func TestMethods(t *testing.T) {
+	vec := "struct V { x, y } "
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{vec + "impl V { fn sum(self) { self.x + self.y } } V { x: 1, y: 2 }.sum()", "3"},
+		{vec + "impl V { fn scale(v, k = 2) { V { x: v.x * k, y: v.y * k } } } let v = V { x: 1, y: 2 }; [v.scale(), v.scale(k: 3)]", "[V { x: 2, y: 4 }, V { x: 3, y: 6 }]"},
+		{vec + "impl V { fn inc(self) { self.x += 1 } } let v = V { x: 1, y: 2 }; v.inc(); v.inc(); v.x", "3"},
+		{vec + "impl V { fn get(self) { self.x } } let f = V { x: 7, y: 0 }.get; f()", "7"},
+		{vec + "impl V { fn a(self) { 1 } } impl V { fn b(self) { self.a() + 1 } } V { x: 0, y: 0 }.b()", "2"},
+		{vec + "impl V { fn get(self) { self.x } } V { x: 0, y: 0 }.get(1)", "ERROR: wrong number of arguments. got=1, want=0"},
+		{vec + "impl V { fn x(self) { 1 } }", "ERROR: method x of V has the name of a field"},
+		{"let V = 1; impl V { fn a(self) { 1 } }", "ERROR: not a struct type: V"},
+		{vec + "V { x: 0, y: 0 }.norm()", "ERROR: struct V has no field norm"},
+		{vec + "impl V { fn __add__(a, b) { V { x: a.x + b.x, y: a.y + b.y } } } V { x: 1, y: 2 } + V { x: 10, y: 20 }", "V { x: 11, y: 22 }"},
+		{vec + "impl V { fn __mul__(a, k) { V { x: a.x * k, y: a.y * k } } } let v = V { x: 1, y: 2 }; v *= 3; v", "V { x: 3, y: 6 }"},
+		{vec + "impl V { fn __lt__(a, b) { a.x < b.x } } V { x: 1, y: 9 } < V { x: 2, y: 0 }", "true"},
+		{vec + "impl V { fn __eq__(a, b) { a.x == b.x } } [V { x: 1, y: 9 } == V { x: 1, y: 0 }, V { x: 1, y: 9 } != V { x: 1, y: 0 }]", "[true, false]"},
+		{vec + "impl V { fn __ne__(a, b) { 42 } } V { x: 1, y: 9 } != 1", "42"},
+		{vec + "V { x: 1, y: 2 } - V { x: 1, y: 2 }", "ERROR: unknown operator: STRUCT - STRUCT"},
+		{vec + "impl V { fn __add__(a, b) { a.x + b } } 1 + V { x: 1, y: 2 }", "ERROR: type mismatch: INTEGER + STRUCT"},
+		{vec + "impl V { fn fail(self) { throw \"boom\" } } try { V { x: 1, y: 2 }.fail() } catch (e) { e.trace }", "[fail (1:83)]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, evaluated.Inspect())
+			}
+		})
+	}
+}
+
+func TestScopes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"const a = 1; a + 1", "2"},
+		{"const a = 1; a = 2;", "ERROR: cannot assign to constant a"},
+		{"const [a, b] = [1, 2]; b = 3;", "ERROR: cannot assign to constant b"},
+		{"const a = 1; let f = fn() { a = 2 }; f()", "ERROR: cannot assign to constant a"},
+		{"const a = 1; if (true) { let a = 2; a = 3; a }", "3"},
+		{"const c = 1; let c = 2; c", "ERROR: cannot redeclare constant c"},
+		{"const c = 1; const [c] = [2]; c", "ERROR: cannot redeclare constant c"},
+		{"let c = 1; const c = 2; c", "2"},
+		{"let a = 1; if (true) { let a = 2; }; a", "1"},
+		{"let a = 1; if (true) { a = 2; }; a", "2"},
+		{"if (true) { let b = 2; }; b", "ERROR: identifier not found: b"},
+		{"for (let i = 0; i < 3; i = i + 1) { let j = i; }; j", "ERROR: identifier not found: j"},
+		{"for (let i = 0; i < 3; i = i + 1) { 1 }; i", "ERROR: identifier not found: i"},
+		{"let n = 0; while (n < 3) { let m = n; n = m + 1; }; n", "3"},
+		{"let n = 0; let inc = fn() { n = n + 1 }; inc(); inc(); n", "2"},
+		{"let s = 0; for (i in range(5)) { s = s + i }; s", "10"},
+		{"let fs = []; for (i in range(3)) { fs = push(fs, fn() { i }) }; [fs[0](), fs[1](), fs[2]()]", "[0, 1, 2]"},
+		{"let n = 0; match (1) { x => { n = x } }; n", "1"},
+		{"try { let t = 1; } finally { 2 }; t", "ERROR: identifier not found: t"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, evaluated.Inspect())
+			}
+		})
+	}
+}
+
 func TestForLoop(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -327,3 +550,458 @@ func testBooleanObject(t *testing.T, obj object.Object, expected bool) {
 		t.Fatalf("object has wrong value, got %t, want %t", result.Value, expected)
 	}
 }
+
+func TestWhileLoop(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let i = 0; while (i < 10) { i = i + 1 }", 10},
+		{"let i = 0; while (i < 10) { i = i + 1; if (i == 5) { break; } }; i", 5},
+		{"let i = 0; let s = 0; while (i < 5) { i = i + 1; if (i == 2) { continue; } s = s + i }; s", 13},
+		{"let s = 0; for (let i = 0; i < 10; i = i + 1) { if (i == 3) { continue; } if (i == 6) { break; } s = s + i }; s", 12},
+		{"let f = fn() { let i = 0; while (true) { i = i + 1; if (i > 3) { return i; } } }; f()", 4},
+		{"let s = 0; outer: for (let i = 0; i < 3; i = i + 1) { for (let j = 0; j < 3; j = j + 1) { if (j == 1) { continue outer; } s = s + 1 } }; s", 3},
+		{"let n = 0; outer: while (true) { while (true) { n = n + 1; break outer; } n = 100 }; n", 1},
+		{"let i = 0; while (true) { let y = if (i > 3) { break; }; i = i + 1; }; i", 4},
+		{"let s = 0; for (let i = 0; i < 5; i = i + 1) { let x = if (i % 2 == 0) { continue; } else { i }; s = s + x }; s", 4},
+		{"let s = 0; for (let i = 0; i < 5; i = i + 1) { s = s + if (i == 3) { break; } else { i } }; s", 3},
+		{"let s = []; for (let i = 0; i < 3; i = i + 1) { s = push(s, if (i == 1) { continue; } else { i }) }; len(s)", 2},
+		{"while (true) { puts(if (true) { break; } else { 1 }) }", nil},
+		{"while (false) { 1 }", nil},
+		{"break;", "break outside of a loop"},
+		{"let f = fn() { continue; }; while (true) { f() }", "continue outside of a loop"},
+		{"while (true) { break nope; }", "break to unknown label nope"},
+		{"while (1 + true) { 1 }", "type mismatch: INTEGER + BOOLEAN"},
+		{"let f = fn(a = if (true) { break; }) { a }; while (true) { f() }", "break outside of a loop"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(tt.input)
+
+			switch expected := tt.expected.(type) {
+			case int:
+				testIntegerObject(t, evaluated, int64(expected))
+			case string:
+				errObj, ok := evaluated.(*object.Error)
+				if !ok {
+					t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+				}
+				if errObj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+				}
+			default:
+				if evaluated != Null {
+					t.Errorf("object is not Null. got=%T (%+v)", evaluated, evaluated)
+				}
+			}
+		})
+	}
+}
+
+func TestCollections(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"hello" + " " + "world"`, "hello world"},
+		{"[1, 2 * 2, 3 + 3]", "[1, 4, 6]"},
+		{"[1, 2, 3][1]", "2"},
+		{"[1, 2, 3][3]", "null"},
+		{`let h = {"one": 1, true: 2, 3: 3}; h["one"] + h[true] + h[3]`, "6"},
+		{`{"b": 1, "a": 2, "b": 3}`, "{b: 3, a: 2}"},
+		{`{"a": 1}["b"]`, "null"},
+		{`len("héllo") + len([1, 2]) + len({1: 1})`, "8"},
+		{"let a = [1, 2, 3]; [first(a), last(a), rest(a), push(a, 4), a]", "[1, 3, [2, 3], [1, 2, 3, 4], [1, 2, 3]]"},
+		{"range(1, 10, 2)", "range(1, 10, 2)"},
+		{`{[1]: 1}`, "ERROR: unusable as hash key: ARRAY"},
+		{"range(0, 1, 0)", "ERROR: range step must not be zero"},
+		{`len(1)`, "ERROR: argument to `len` not supported, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, evaluated.Inspect())
+			}
+		})
+	}
+}
+
+func TestForInLoop(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"for (i in range(5)) { i * 2 }", "8"},
+		{"for (i in range(10, 0, -3)) { i }", "1"},
+		{"for (i, x in [5, 6]) { [i, x] }", "[1, 6]"},
+		{`for (c in "abc") { c }`, "c"},
+		{`for (k in {"a": 1, "b": 2}) { k }`, "b"},
+		{`for (k, v in {"a": 1, "b": 2}) { [k, v] }`, "[b, 2]"},
+		{"let f = for (i in range(3)) { let g = fn() { i }; if (i == 1) { break; } g }; f()", "0"},
+		{"for (i in range(10)) { if (i == 2) { continue; } if (i == 5) { break; } i }", "4"},
+		{"outer: for (i in range(3)) { for (j in range(3)) { if (i == 2) { break outer; }; [i, j] } }", "[1, 2]"},
+		{"let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { return x; } } }; f()", "2"},
+		{"let i = 10; for (i in range(2)) { i }; i", "10"},
+		{"let n = 0; for (i in range(9223372036854775805, 9223372036854775807, 3)) { n = n + 1 }; n", "1"},
+		{"let n = 0; for (i in range(-9223372036854775806, -9223372036854775807 - 1, -5)) { n = n + 1 }; n", "1"},
+		{"for (x in 5) { x }", "ERROR: not iterable: INTEGER"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, evaluated.Inspect())
+			}
+		})
+	}
+}
+
+func TestExceptions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`try { throw "oops"; 1 } catch (e) { e["message"] }`, "oops"},
+		{`try { 1 } catch (e) { 2 }`, "1"},
+		{`try { 1 / 0 } catch (e) { [e["kind"], e["message"]] }`, "[ArithmeticError, division by zero]"},
+		{`try { missing } catch (e) { e["kind"] }`, "NameError"},
+		{`try { 1 + true } catch (e) { e }`, "TypeError: type mismatch: INTEGER + BOOLEAN"},
+		{`try { throw error("bad input", "ValueError") } catch (e) { e["kind"] }`, "ValueError"},
+		{`try { throw 42 } catch (e) { e["message"] }`, "42"},
+		{`try { throw "boom" } catch (e) { e.message }`, "boom"},
+		{`let e = 0; try { throw 1 } catch (e) { e }; e`, "0"},
+		{`let f = fn() { throw "deep" }; let g = fn() { f() }; try { g() } catch (e) { e["trace"] }`, "[f (1:47), g (1:60)]"},
+		{`try { try { throw "inner" } catch (e) { throw e } } catch (e) { e["message"] }`, "inner"},
+		{`let n = 0; try { n = 1 } finally { n = n + 10 }; n`, "11"},
+		{`let n = 0; try { try { throw "x" } finally { n = 1 } } catch (e) { n }`, "1"},
+		{`let f = fn() { try { return 1 } finally { return 2 } }; f()`, "2"},
+		{`let f = fn() { try { return 1 } finally { throw "late" } }; try { f() } catch (e) { e["message"] }`, "late"},
+		{`let s = 0; for (let i = 0; i < 5; i = i + 1) { try { if (i == 3) { break } s = s + i } finally { s = s + 100 } }; s`, "403"},
+		{`let e = try { throw "kept" } catch (e) { e }; let m = e; m["kind"]`, "Error"},
+		{`try { throw "first" } catch (e) { throw "second" }`, "ERROR: second"},
+		{`try { throw "lost" } finally { 1 }`, "ERROR: lost"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, evaluated.Inspect())
+			}
+		})
+	}
+}
+
+func TestResults(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"ok(1)", "ok(1)"},
+		{`err("no")`, "err(no)"},
+		{"[is_ok(ok(1)), is_ok(err(1)), is_err(err(1))]", "[true, false, true]"},
+		{"unwrap(ok(5))", "5"},
+		{`unwrap(err("no"))`, "ERROR: unwrap of err(no)"},
+		{`unwrap(try { throw "raised" } catch (e) { err(e) })`, "ERROR: raised"},
+		{"[unwrap_or(ok(1), 2), unwrap_or(err(1), 2)]", "[1, 2]"},
+		{"unwrap(1)", "ERROR: argument to `unwrap` must be RESULT, got INTEGER"},
+		{"let f = fn(r) { let v = r?; ok(v + 1) }; [f(ok(1)), f(err(0))]", "[ok(2), err(0)]"},
+		{"let f = fn(r) { 10 * r? + 1 }; [f(ok(2)), f(err(3))]", "[21, err(3)]"},
+		{"let f = fn(a, b) { ok(a? + b?) }; f(ok(1), err(2))", "err(2)"},
+		{"let f = fn(rs) { for (r in rs) { r? }; ok(0) }; f([ok(1), err(2), ok(3)])", "err(2)"},
+		{"let inner = fn() { err(1)? }; let outer = fn() { inner(); ok(2) }; outer()", "ok(2)"},
+		{"err(1)?; 2", "err(1)"},
+		{"5?", "ERROR: unknown operator: INTEGER?"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, evaluated.Inspect())
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match (0) { 0 => \"zero\", _ => \"other\" }", "zero"},
+		{"match (-2) { -2 => 1, _ => 2 }", "1"},
+		{"match (2.0) { 2 => \"int\", _ => \"other\" }", "int"},
+		{`match ("b") { "a" => 1, "b" => 2 }`, "2"},
+		{"match (true) { false => 0, true => 1 }", "1"},
+		{"match (42) { n => n + 1 }", "43"},
+		{"match (15) { x if x > 10 => \"big\", x => \"small\" }", "big"},
+		{"match (5) { x if x > 10 => \"big\", x => \"small\" }", "small"},
+		{"match ([1, 2, 3]) { [] => 0, [first, ...rest] => [first, rest] }", "[1, [2, 3]]"},
+		{"match ([]) { [] => \"empty\", [x, ..._] => x }", "empty"},
+		{"match ([1, 2]) { [x] => 1, [x, y, z] => 3, [x, y] => x + y }", "3"},
+		{"match ([1, [2, 3]]) { [a, [b, c]] => a + b + c }", "6"},
+		{`match ({"k": 1, "x": 2}) { {"k": v} => v }`, "1"},
+		{`match ({"k": 1}) { {"j": v} => v, {"k": 2} => 2, _ => 3 }`, "3"},
+		{"let sum = fn(xs) { match (xs) { [] => 0, [x, ...rest] => x + sum(rest) } }; sum([1, 2, 3, 4])", "10"},
+		{"let x = 1; match (2) { x => x }; x", "1"},
+		{"match (\"s\") { 1 => 1, [] => 2, {} => 3 }", "ERROR: no pattern matches s"},
+		{"match (1) { x if missing => 1 }", "ERROR: identifier not found: missing"},
+		{"let f = fn() { match (1) { 1 => { return 5; } } 6 }; f()", "5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, evaluated.Inspect())
+			}
+		})
+	}
+}
+
+func TestDestructuring(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b, ...rest] = [1, 2, 3, 4]; [a, b, rest]", "[1, 2, [3, 4]]"},
+		{"let [a, ...rest] = [1]; rest", "[]"},
+		{`let {name, age} = {"name": "ann", "age": 30, "x": 0}; [name, age]`, "[ann, 30]"},
+		{`let {user: {name: n}, "tags": [first, ..._]} = {"user": {"name": "bo"}, "tags": [1, 2]}; [n, first]`, "[bo, 1]"},
+		{"let f = fn({x, y}) { x * y }; f({\"x\": 3, \"y\": 4})", "12"},
+		{"let head = fn([h, ..._], d) { h + d }; head([5, 6], 1)", "6"},
+		{"let [a, b] = [1];", "ERROR: array of length 1 doesn't match [a, b]"},
+		{"let [a] = 5;", "ERROR: cannot destructure INTEGER as an array"},
+		{`let {name} = {"age": 1};`, `ERROR: hash has no key "name"`},
+		{"let {x} = [1];", "ERROR: cannot destructure ARRAY as a hash"},
+		{"let f = fn({x}) { x }; f(1)", "ERROR: cannot destructure INTEGER as a hash"},
+		{`try { let [a, b] = [] } catch (e) { e["kind"] }`, "Error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, evaluated.Inspect())
+			}
+		})
+	}
+}
+
+func TestParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let f = fn(a, b = a * 2) { [a, b] }; [f(1), f(1, 5)]", "[[1, 2], [1, 5]]"},
+		{"let f = fn(first, ...others) { [first, others] }; [f(1), f(1, 2, 3)]", "[[1, []], [1, [2, 3]]]"},
+		{"let f = fn(a, b, c) { a - b - c }; f(...[10, 2], 3)", "5"},
+		{"let f = fn(a, b = 2, c = 3) { [a, b, c] }; f(c: 30, a: 1)", "[1, 2, 30]"},
+		{"let f = fn(a, b) { a + b }; f(1)", "ERROR: wrong number of arguments. got=1, want=2"},
+		{"let f = fn(a, b) { a + b }; f(1, 2, 3)", "ERROR: wrong number of arguments. got=3, want=2"},
+		{"let f = fn(a, b = 1) { a }; f()", "ERROR: wrong number of arguments. got=0, want=1 to 2"},
+		{"let f = fn(a, ...b) { a }; f()", "ERROR: wrong number of arguments. got=0, want=at least 1"},
+		{"let f = fn(a, b) { a }; f(b: 1)", "ERROR: missing argument for parameter a"},
+		{"let f = fn(a) { a }; f(1, a: 2)", "ERROR: multiple values for parameter a"},
+		{"let f = fn(a) { a }; f(b: 2)", "ERROR: unexpected keyword argument b"},
+		{"len(x: [])", "ERROR: unexpected keyword argument x"},
+		{"let f = fn(a) { a }; f(...1)", "ERROR: cannot spread INTEGER"},
+		{`try { fn(a) { a }() } catch (e) { e["kind"] }`, "TypeError"},
+		{`let f = fn(a = err("bad")?) { "ran" }; f()`, "err(bad)"},
+		{`let f = fn(a = ok(1)?) { a + 1 }; f()`, "2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, evaluated.Inspect())
+			}
+		})
+	}
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"7 % 3", "1"},
+		{"1 <= 1", "true"},
+		{"2 <= 1", "false"},
+		{"1 >= 2", "false"},
+		{"true && false", "false"},
+		{"true || false", "true"},
+		{"1 && 0", "true"},
+		{"if (false) { 1 } && true", "false"},
+		{"false || 2", "true"},
+		{"false && missing", "false"},
+		{"true || missing", "true"},
+		{"true && missing", "ERROR: identifier not found: missing"},
+		{"let n = 0; let f = fn() { n = n + 1; true }; false && f(); true || f(); n", "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, evaluated.Inspect())
+			}
+		})
+	}
+}
+
+func TestBitwiseOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"12 & 10", "8"},
+		{"0xFF & 0b1010 | 0o100", "74"},
+		{"1_000_000 / 1_000", "1000"},
+		{"12 | 10", "14"},
+		{"12 ^ 10", "6"},
+		{"~5", "-6"},
+		{"1 << 4", "16"},
+		{"-16 >> 2", "-4"},
+		{"-16 >>> 60", "15"},
+		{"1 << 64", "18446744073709551616"},
+		{"1 | 2 ^ 3 & 4", "3"},
+		{"1 << -1", "ERROR: negative shift count: -1"},
+		{"~true", "ERROR: unknown operator: ~BOOLEAN"},
+		{"true & false", "ERROR: unknown operator: BOOLEAN & BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, evaluated.Inspect())
+			}
+		})
+	}
+}
+
+func TestFloats(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"3.14", "3.14"},
+		{"1e-9", "1e-09"},
+		{"-.5 * 4", "-2.0"},
+		{"7 / 2", "3"},
+		{"7 / 2.0", "3.5"},
+		{"1 + 0.5", "1.5"},
+		{"7.5 % 2", "1.5"},
+		{"1 / 0.0", "ERROR: division by zero"},
+		{"1 == 1.0", "true"},
+		{"2.5 >= 3", "false"},
+		{"int(2.9) + int(-2.9)", "0"},
+		{"float(3) / 2", "1.5"},
+		{`int("42") + float("0.5")`, "42.5"},
+		{`{1.5: "a"}[1.5]`, "a"},
+		{"1.5 & 1", "ERROR: unknown operator: FLOAT & INTEGER"},
+		{"int(1e308 * 10)", "ERROR: cannot convert +Inf to an integer"},
+		{`float("x")`, `ERROR: cannot convert "x" to a float`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			evaluated := testEval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, evaluated.Inspect())
+			}
+		})
+	}
+}
+
+func TestIntegerOverflow(t *testing.T) {
+	tests := []struct {
+		input    string
+		promoted string
+		strict   string
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808", "ERROR: integer overflow: 9223372036854775807 + 1"},
+		{"-9223372036854775807 - 2", "-9223372036854775809", "ERROR: integer overflow: -9223372036854775807 - 2"},
+		{"4294967296 * 4294967296", "18446744073709551616", "ERROR: integer overflow: 4294967296 * 4294967296"},
+		{"let m = -9223372036854775807 - 1; -m", "9223372036854775808", "ERROR: integer overflow: -(-9223372036854775808)"},
+		{"let m = -9223372036854775807 - 1; m / -1", "9223372036854775808", "ERROR: integer overflow: -9223372036854775808 / -1"},
+		{"-1 << 63", "-9223372036854775808", "-9223372036854775808"},
+		{"3 << 62", "13835058055282163712", "ERROR: integer overflow: 3 << 62"},
+		{"9223372036854775807 * 3 / 3", "9223372036854775807", "ERROR: integer overflow: 9223372036854775807 * 3"},
+		{"let f = fn(n) { let r = 1; for (let i = 1; i <= n; i = i + 1) { r = r * i }; r }; f(25)", "15511210043330985984000000", "ERROR: integer overflow: 2432902008176640000 * 21"},
+		{"(1 << 100) % 7 + (1 << 100 >> 98)", "6", "ERROR: integer overflow: 1 << 100"},
+		{"(1 << 64) > 1 && (1 << 64) == (1 << 64) && -(1 << 64) < 0", "true", "ERROR: integer overflow: 1 << 64"},
+		{"(1 << 64) & ~0", "18446744073709551616", "ERROR: integer overflow: 1 << 64"},
+		{"(1 << 64) + 0.5", "1.8446744073709552e+19", "ERROR: integer overflow: 1 << 64"},
+		{`int("123456789012345678901234567890")`, "123456789012345678901234567890", "ERROR: integer overflow: int returned 123456789012345678901234567890"},
+		{`int("99999999999999999999") + 1`, "100000000000000000000", "ERROR: integer overflow: int returned 99999999999999999999"},
+		{"int(1e30) > 0", "true", "ERROR: integer overflow: int returned 1000000000000000019884624838656"},
+		{"9223372036854775808", "9223372036854775808", "ERROR: integer overflow: 9223372036854775808"},
+		{"0x1_0000_0000_0000_0000 - 1", "18446744073709551615", "ERROR: integer overflow: 0x1_0000_0000_0000_0000"},
+		{"-9223372036854775808", "-9223372036854775808", "ERROR: integer overflow: 9223372036854775808"},
+		{`{1 << 64: "big"}[2 << 63]`, "big", "ERROR: integer overflow: 1 << 64"},
+		{"(1 << 64) >>> 1", "9223372036854775808", "ERROR: integer overflow: 1 << 64"},
+		{"-1 >>> (1 << 64)", "ERROR: shift count too large: 18446744073709551616", "ERROR: integer overflow: 1 << 64"},
+		{"-(1 << 64) >>> 1", "ERROR: unsigned shift of a negative BigInt: -18446744073709551616", "ERROR: integer overflow: 1 << 64"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program := parser.New(lexer.New(tt.input)).ParseProgram()
+
+			if evaluated := Eval(program, object.NewEnvironment()); evaluated.Inspect() != tt.promoted {
+				t.Errorf("expected %q, got %q", tt.promoted, evaluated.Inspect())
+			}
+
+			env := object.NewEnvironment()
+			env.SetOverflowMode(object.ErrorOnOverflow)
+			if evaluated := Eval(program, env); evaluated.Inspect() != tt.strict {
+				t.Errorf("strict: expected %q, got %q", tt.strict, evaluated.Inspect())
+			}
+		})
+	}
+}
+
+func TestInternalError(t *testing.T) {
+	// An index expression without operands can't come from the parser, it
+	// makes the evaluator panic
+	node := &ast.IndexExpression{}
+
+	evaluated := Eval(node, object.NewEnvironment())
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got %T", evaluated)
+	}
+
+	if !strings.HasPrefix(errObj.Message, "internal error: ") {
+		t.Errorf("expected an internal error, got %q", errObj.Message)
+	}
+	if !strings.Contains(errObj.Trace, "evalIndexExpression") {
+		t.Errorf("expected the trace to show where the panic happened, got %q", errObj.Trace)
+	}
+
+	if errObj := testEval("1 / 0").(*object.Error); errObj.Trace != "" {
+		t.Errorf("expected no trace for an error of the program, got %q", errObj.Trace)
+	}
+}
+
+func TestBuiltinNames(t *testing.T) {
+	for _, name := range object.BuiltinNames {
+		if _, ok := Builtins[name]; !ok {
+			t.Errorf("builtin %s is not implemented", name)
+		}
+	}
+	for name := range Builtins {
+		if !object.IsBuiltin(name) {
+			t.Errorf("builtin %s is missing from object.BuiltinNames", name)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package eval
+
+import (
+	"github.com/rumpl/monkey-lang/ast"
+	"github.com/rumpl/monkey-lang/object"
+)
+
+// evalMatchExpression evaluates the body of the first arm whose pattern
+// matches the subject and whose guard is truthy. Every arm binds its pattern
+// variables in its own environment.
+func evalMatchExpression(me *ast.MatchExpression, env *object.Environment) object.Object {
+	subject := evalNode(me.Subject, env)
+	if isAbrupt(subject) {
+		return subject
+	}
+
+	for _, arm := range me.Arms {
+		armEnv := object.NewEnclosedEnvironment(env)
+		if bindPattern(arm.Pattern, subject, armEnv) != nil {
+			continue
+		}
+
+		if arm.Guard != nil {
+			guard := evalNode(arm.Guard, armEnv)
+			if isAbrupt(guard) {
+				return guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+
+		return evalNode(arm.Body, armEnv)
+	}
+
+	return newError("no pattern matches %s", subject.Inspect())
+}
+
+// bindPattern binds the variables of pattern to the parts of value in env,
+// the error tells how value doesn't match the pattern. The bindings made
+// before a mismatch are left in env.
+func bindPattern(pattern ast.Pattern, value object.Object, env *object.Environment) *object.Error {
+	switch pattern := pattern.(type) {
+	case *ast.WildcardPattern:
+		return nil
+	case *ast.Identifier:
+		env.Set(pattern.Value, value)
+		return nil
+	case *ast.ArrayPattern:
+		return bindArrayPattern(pattern, value, env)
+	case *ast.HashPattern:
+		return bindHashPattern(pattern, value, env)
+	default:
+		// A literal, the parser doesn't allow anything else
+		literal := evalNode(pattern, env)
+		if evalInfixExpression("==", value, literal, object.PromoteOnOverflow) != True {
+			return newError("%s doesn't match %s", value.Inspect(), pattern)
+		}
+		return nil
+	}
+}
+
+func bindArrayPattern(pattern *ast.ArrayPattern, value object.Object, env *object.Environment) *object.Error {
+	arr, ok := value.(*object.Array)
+	if !ok {
+		return newKindError(object.TypeError, "cannot destructure %s as an array", value.Type())
+	}
+
+	n := len(pattern.Elements)
+	if len(arr.Elements) < n || (pattern.Rest == nil && len(arr.Elements) != n) {
+		return newError("array of length %d doesn't match %s", len(arr.Elements), pattern)
+	}
+
+	for i, el := range pattern.Elements {
+		if err := bindPattern(el, arr.Elements[i], env); err != nil {
+			return err
+		}
+	}
+
+	if pattern.Rest != nil && pattern.Rest.Value != "_" {
+		rest := make([]object.Object, len(arr.Elements)-n)
+		copy(rest, arr.Elements[n:])
+		env.Set(pattern.Rest.Value, &object.Array{Elements: rest})
+	}
+
+	return nil
+}
+
+func bindHashPattern(pattern *ast.HashPattern, value object.Object, env *object.Environment) *object.Error {
+	hash, ok := value.(*object.Hash)
+	if !ok {
+		return newKindError(object.TypeError, "cannot destructure %s as a hash", value.Type())
+	}
+
+	for _, pair := range pattern.Pairs {
+		key := evalNode(pair.Key, env)
+		v, ok := hash.Get(key.(object.Hashable))
+		if !ok {
+			return newError("hash has no key %s", pair.Key)
+		}
+		if err := bindPattern(pair.Value, v, env); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package eval
+
+import (
+	"github.com/rumpl/monkey-lang/ast"
+	"github.com/rumpl/monkey-lang/object"
+)
+
+// evalStructLiteral builds a value of a struct type, every field of the type
+// must be given and no other
+func evalStructLiteral(sl *ast.StructLiteral, env *object.Environment) object.Object {
+	t := evalIdentifier(sl.Name, env)
+	if isAbrupt(t) {
+		return t
+	}
+	structType, ok := t.(*object.StructType)
+	if !ok {
+		return newKindError(object.TypeError, "not a struct type: %s", sl.Name.Value)
+	}
+
+	s := &object.Struct{StructType: structType, Fields: map[string]object.Object{}}
+	for _, field := range sl.Fields {
+		if !structType.HasField(field.Name.Value) {
+			return newKindError(object.TypeError, "struct %s has no field %s", structType.Name, field.Name.Value)
+		}
+
+		value := evalNode(field.Value, env)
+		if isAbrupt(value) {
+			return value
+		}
+		s.Fields[field.Name.Value] = value
+	}
+
+	for _, name := range structType.Fields {
+		if _, ok := s.Fields[name]; !ok {
+			return newKindError(object.TypeError, "missing field %s of %s", name, structType.Name)
+		}
+	}
+
+	return s
+}
+
+// evalStructField gives the value of a field of a struct, or one of its
+// methods bound to it
+func evalStructField(s *object.Struct, field string) object.Object {
+	if value, ok := s.Fields[field]; ok {
+		return value
+	}
+	if method := s.StructType.Method(field); method != nil {
+		return bindMethod(method, s)
+	}
+	return newKindError(object.TypeError, "struct %s has no field %s", s.StructType.Name, field)
+}
+
+// evalImplStatement adds methods to a struct type, a method cannot have the
+// name of a field
+func evalImplStatement(is *ast.ImplStatement, env *object.Environment) object.Object {
+	t := evalIdentifier(is.Name, env)
+	if isAbrupt(t) {
+		return t
+	}
+	structType, ok := t.(*object.StructType)
+	if !ok {
+		return newKindError(object.TypeError, "not a struct type: %s", is.Name.Value)
+	}
+
+	for _, m := range is.Methods {
+		if structType.HasField(m.Name) {
+			return newKindError(object.TypeError, "method %s of %s has the name of a field", m.Name, structType.Name)
+		}
+		structType.Methods[m.Name] = &object.Function{Parameters: m.Parameters, Env: env, Body: m.Body}
+	}
+
+	return nil
+}
+
+// bindMethod gives a function calling method with its receiver, the first
+// parameter, bound to s
+func bindMethod(method *object.Function, s *object.Struct) *object.Function {
+	env := object.NewEnclosedEnvironment(method.Env)
+	env.Set(method.Parameters[0].Variable().Value, s)
+
+	return &object.Function{Parameters: method.Parameters[1:], Env: env, Body: method.Body}
+}
+
+// operatorMethods are the methods a struct can have to implement the infix
+// operators
+var operatorMethods = map[string]string{
+	"+":  "__add__",
+	"-":  "__sub__",
+	"*":  "__mul__",
+	"/":  "__div__",
+	"%":  "__mod__",
+	"==": "__eq__",
+	"!=": "__ne__",
+	"<":  "__lt__",
+	">":  "__gt__",
+	"<=": "__le__",
+	">=": "__ge__",
+}
+
+// evalOperatorMethod applies an infix operator to a struct having the method
+// implementing it, the struct being the left operand. Without __ne__, != is
+// the negation of __eq__.
+func evalOperatorMethod(operator string, left, right object.Object) (object.Object, bool) {
+	s, ok := left.(*object.Struct)
+	if !ok {
+		return nil, false
+	}
+
+	if method := s.StructType.Method(operatorMethods[operator]); method != nil {
+		return applyFunction(bindMethod(method, s), []object.Object{right}, nil), true
+	}
+
+	if eq := s.StructType.Method("__eq__"); eq != nil && operator == "!=" {
+		result := applyFunction(bindMethod(eq, s), []object.Object{right}, nil)
+		if isAbrupt(result) {
+			return result, true
+		}
+		return nativeBoolToBooleanObject(!isTruthy(result)), true
+	}
+
+	return nil, false
+}
+
+// structsEqual tells if two structs are of the same type and hold equal
+// values in all their fields
+func structsEqual(left, right *object.Struct, mode object.OverflowMode) bool {
+	if left.StructType != right.StructType {
+		return false
+	}
+
+	for _, name := range left.StructType.Fields {
+		if evalInfixExpression("==", left.Fields[name], right.Fields[name], mode) != True {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,829 @@
+package jsgen
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rumpl/monkey-lang/ast"
+	"github.com/rumpl/monkey-lang/token"
+)
+
+// helpers are small runtime functions emitted at the end of the generated
+// code when used. Function declarations are hoisted so they can come last,
+// which keeps the source map offsets of the program untouched.
+var helpers = map[string]string{
+	"$truthy": `function $truthy(v) {
+  return v !== false && v !== null;
+}`,
+	"$not": `function $not(v) {
+  return v === false || v === null;
+}`,
+	"$div": `function $div(a, b) {
+  if (b === 0n) {
+    throw new RangeError("division by zero");
+  }
+  return a / b;
+}`,
+	"$mod": `function $mod(a, b) {
+  if (b === 0n) {
+    throw new RangeError("division by zero");
+  }
+  return a % b;
+}`,
+	"$shl": `function $shl(a, b) {
+  if (b < 0n) {
+    throw new RangeError("negative shift count");
+  }
+  return a << b;
+}`,
+	"$shr": `function $shr(a, b) {
+  if (b < 0n) {
+    throw new RangeError("negative shift count");
+  }
+  return a >> b;
+}`,
+	"$ushr": `function $ushr(a, b) {
+  if (b < 0n) {
+    throw new RangeError("negative shift count");
+  }
+  return BigInt.asIntN(64, BigInt.asUintN(64, a) >> b);
+}`,
+}
+
+// operatorHelpers are the helpers implementing the infix operators that
+// JavaScript doesn't have for integers, or that must fail like in monkey
+var operatorHelpers = map[string]string{
+	"/":   "$div",
+	"%":   "$mod",
+	"<<":  "$shl",
+	">>":  "$shr",
+	">>>": "$ushr",
+}
+
+var reserved = map[string]bool{
+	"arguments": true, "await": true, "break": true, "case": true, "catch": true,
+	"class": true, "const": true, "continue": true, "debugger": true, "default": true,
+	"delete": true, "do": true, "enum": true, "eval": true, "export": true,
+	"extends": true, "finally": true, "function": true, "implements": true,
+	"import": true, "in": true, "instanceof": true, "interface": true, "new": true,
+	"null": true, "package": true, "private": true, "protected": true, "public": true,
+	"static": true, "super": true, "switch": true, "this": true, "throw": true,
+	"try": true, "typeof": true, "undefined": true, "var": true, "void": true,
+	"while": true, "with": true, "yield": true, "NaN": true, "Infinity": true,
+}
+
+// sink tells a statement what to do with the value it produces
+type sink struct {
+	kind   sinkKind
+	target string
+}
+
+type sinkKind int
+
+const (
+	discard sinkKind = iota
+	ret
+	assign
+)
+
+// JS translates a monkey program into JavaScript
+type JS struct {
+	program ast.Node
+	source  string
+
+	out    bytes.Buffer
+	line   int
+	column int
+	indent int
+
+	mappings []mapping
+	names    []string
+	nameIdx  map[string]int
+
+	scopes  []map[string]bool
+	helpers map[string]bool
+	temps   int
+	depth   int // function nesting depth, 0 at the top level
+
+	errors []string
+}
+
+// New returns a generator for program, source is the name of the monkey
+// file the program was parsed from and is recorded in the source map
+func New(program ast.Node, source string) *JS {
+	return &JS{
+		program: program,
+		source:  source,
+		nameIdx: make(map[string]int),
+		helpers: make(map[string]bool),
+	}
+}
+
+// Generate returns the JavaScript code for the program and its source map,
+// file is the name of the generated file.
+func (j *JS) Generate(file string) (string, *SourceMap, error) {
+	j.pushScope()
+	j.node(j.program)
+	j.popScope()
+
+	if len(j.errors) > 0 {
+		return "", nil, fmt.Errorf("%s", strings.Join(j.errors, "\n"))
+	}
+
+	j.write("\n")
+
+	names := []string{}
+	for name := range j.helpers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		j.write("\n" + helpers[name] + "\n")
+	}
+
+	j.write("//# sourceMappingURL=" + file + ".map\n")
+
+	sm := &SourceMap{
+		Version:  3,
+		File:     file,
+		Sources:  []string{j.source},
+		Names:    j.names,
+		Mappings: encodeMappings(j.mappings),
+	}
+	if sm.Names == nil {
+		sm.Names = []string{}
+	}
+
+	return j.out.String(), sm, nil
+}
+
+func (j *JS) errorf(pos token.Position, format string, a ...interface{}) {
+	msg := pos.String() + ": " + fmt.Sprintf(format, a...)
+	j.errors = append(j.errors, msg)
+}
+
+func (j *JS) write(s string) {
+	j.out.WriteString(s)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		j.line += strings.Count(s, "\n")
+		j.column = len(s) - i - 1
+	} else {
+		j.column += len(s)
+	}
+}
+
+func (j *JS) newline() {
+	if j.out.Len() == 0 {
+		return
+	}
+	j.write("\n" + strings.Repeat("  ", j.indent))
+}
+
+// mark maps the current position in the generated code to the position of
+// pos in the monkey source
+func (j *JS) mark(pos token.Position, name string) {
+	if pos.Line == 0 {
+		return
+	}
+
+	m := mapping{
+		genLine:   j.line,
+		genColumn: j.column,
+		srcLine:   pos.Line - 1,
+		srcColumn: pos.Column - 1,
+		name:      -1,
+	}
+
+	if name != "" {
+		idx, ok := j.nameIdx[name]
+		if !ok {
+			idx = len(j.names)
+			j.names = append(j.names, name)
+			j.nameIdx[name] = idx
+		}
+		m.name = idx
+	}
+
+	j.mappings = append(j.mappings, m)
+}
+
+func (j *JS) pushScope() {
+	j.scopes = append(j.scopes, make(map[string]bool))
+}
+
+func (j *JS) popScope() {
+	j.scopes = j.scopes[:len(j.scopes)-1]
+}
+
+// declare records name in the current scope and reports whether it was
+// already declared there. Monkey allows re-declaring a name with let, which
+// is an error in JavaScript, so it becomes an assignment instead.
+func (j *JS) declare(name string) bool {
+	scope := j.scopes[len(j.scopes)-1]
+	if scope[name] {
+		return true
+	}
+	scope[name] = true
+	return false
+}
+
+func (j *JS) temp() string {
+	j.temps++
+	return fmt.Sprintf("$%d", j.temps)
+}
+
+func (j *JS) helper(name string) string {
+	j.helpers[name] = true
+	return name
+}
+
+func identifier(name string) string {
+	if reserved[name] {
+		return name + "$"
+	}
+	return name
+}
+
+func (j *JS) node(node ast.Node) {
+	switch node := node.(type) {
+	case *ast.Program:
+		j.statements(node.Statements, sink{kind: discard})
+	case ast.Statement:
+		j.statement(node, sink{kind: discard})
+	}
+}
+
+func (j *JS) statements(stmts []ast.Statement, s sink) {
+	for i, stmt := range stmts {
+		if i == len(stmts)-1 {
+			j.statement(stmt, s)
+		} else {
+			j.statement(stmt, sink{kind: discard})
+		}
+	}
+
+	if len(stmts) == 0 {
+		j.null(s)
+	}
+}
+
+func (j *JS) statement(stmt ast.Statement, s sink) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		j.letStatement(stmt)
+		j.null(s)
+	case *ast.ReturnStatement:
+		if j.depth == 0 {
+			j.errorf(stmt.Pos(), "return outside of a function is not supported")
+			return
+		}
+		j.newline()
+		j.mark(stmt.Pos(), "")
+		j.write("return ")
+		j.expression(stmt.ReturnValue)
+		j.write(";")
+	case *ast.FunctionStatement:
+		j.functionStatement(stmt)
+		j.null(s)
+	case *ast.ExpressionStatement:
+		switch exp := stmt.Expression.(type) {
+		case *ast.IfExpression:
+			j.ifStatement(exp, s)
+		case *ast.ForExpression:
+			j.forStatement(exp, s)
+		case *ast.WhileExpression:
+			j.whileStatement(exp, s)
+		default:
+			j.value(s, func() { j.expression(stmt.Expression) })
+		}
+	case *ast.BreakStatement:
+		j.jump(stmt, stmt.Label)
+	case *ast.ContinueStatement:
+		j.jump(stmt, stmt.Label)
+	case *ast.StructStatement:
+		j.errorf(stmt.Pos(), "structs are not supported")
+	case *ast.ImplStatement:
+		j.errorf(stmt.Pos(), "methods are not supported")
+	default:
+		j.errorf(stmt.Pos(), "unsupported statement %T", stmt)
+	}
+}
+
+// jump writes a break or a continue, JavaScript has the same labels
+func (j *JS) jump(stmt ast.Statement, label *ast.Identifier) {
+	j.newline()
+	j.mark(stmt.Pos(), "")
+	j.write(stmt.TokenLiteral())
+	if label != nil {
+		j.write(" " + identifier(label.Value))
+	}
+	j.write(";")
+}
+
+// value writes the code produced by emit as a statement giving its value to s
+func (j *JS) value(s sink, emit func()) {
+	j.newline()
+	switch s.kind {
+	case ret:
+		j.write("return ")
+	case assign:
+		j.write(s.target + " = ")
+	}
+	emit()
+	j.write(";")
+}
+
+func (j *JS) null(s sink) {
+	if s.kind != discard {
+		j.value(s, func() { j.write("null") })
+	}
+}
+
+func (j *JS) letStatement(stmt *ast.LetStatement) {
+	id := stmt.Name.Variable()
+	if id == nil {
+		j.errorf(stmt.Name.Pos(), "destructuring is not supported")
+		return
+	}
+
+	name := identifier(id.Value)
+	redeclared := j.declare(name)
+
+	switch value := stmt.Value.(type) {
+	case *ast.IfExpression, *ast.ForExpression, *ast.WhileExpression:
+		if !redeclared {
+			j.newline()
+			j.mark(stmt.Pos(), "")
+			j.write("let ")
+			j.mark(id.Pos(), id.Value)
+			j.write(name + ";")
+		}
+		j.statement(&ast.ExpressionStatement{Expression: value}, sink{kind: assign, target: name})
+		return
+	}
+
+	j.newline()
+	j.mark(stmt.Pos(), "")
+	if !redeclared {
+		j.write(stmt.TokenLiteral() + " ")
+	}
+	j.mark(id.Pos(), id.Value)
+	j.write(name + " = ")
+	j.expression(stmt.Value)
+	j.write(";")
+}
+
+func (j *JS) functionStatement(stmt *ast.FunctionStatement) {
+	j.declare(identifier(stmt.Name))
+
+	j.newline()
+	j.mark(stmt.Pos(), "")
+	j.write("function " + identifier(stmt.Name))
+	j.parameters(stmt.Parameters)
+	j.write(" ")
+	j.functionBody(stmt.Body)
+}
+
+func (j *JS) parameters(params []*ast.Declaration) {
+	j.write("(")
+	for i, p := range params {
+		if i > 0 {
+			j.write(", ")
+		}
+		id := p.Variable()
+		switch {
+		case id == nil:
+			j.errorf(p.Pos(), "destructuring is not supported")
+			continue
+		case p.Default != nil:
+			j.errorf(p.Pos(), "default parameters are not supported")
+			continue
+		case p.Rest:
+			j.errorf(p.Pos(), "rest parameters are not supported")
+			continue
+		}
+		j.mark(id.Pos(), id.Value)
+		j.write(identifier(id.Value))
+	}
+	j.write(")")
+}
+
+func (j *JS) functionBody(body *ast.BlockStatement) {
+	j.depth++
+	j.pushScope()
+	j.block(body, sink{kind: ret})
+	j.popScope()
+	j.depth--
+}
+
+func (j *JS) block(body *ast.BlockStatement, s sink) {
+	j.write("{")
+	j.indent++
+	j.statements(body.Statements, s)
+	j.indent--
+	j.newline()
+	j.write("}")
+}
+
+func (j *JS) scopedBlock(body *ast.BlockStatement, s sink) {
+	j.pushScope()
+	j.block(body, s)
+	j.popScope()
+}
+
+func (j *JS) ifStatement(exp *ast.IfExpression, s sink) {
+	j.newline()
+	j.mark(exp.Pos(), "")
+	j.write("if (")
+	j.condition(exp.Condition)
+	j.write(") ")
+	j.scopedBlock(exp.Consequence, s)
+
+	if exp.Alternative != nil {
+		j.write(" else ")
+		j.scopedBlock(exp.Alternative, s)
+	} else if s.kind != discard {
+		j.write(" else {")
+		j.indent++
+		j.null(s)
+		j.indent--
+		j.newline()
+		j.write("}")
+	}
+}
+
+func (j *JS) forStatement(exp *ast.ForExpression, s sink) {
+	body := sink{kind: discard}
+	if s.kind != discard {
+		body = sink{kind: assign, target: j.temp()}
+		j.newline()
+		j.write("let " + body.target + " = null;")
+	}
+
+	j.pushScope()
+	j.newline()
+	j.label(exp.Label)
+	j.mark(exp.Pos(), "")
+	j.write("for (")
+	if init, ok := exp.Initial.(*ast.LetStatement); ok && init.Name.Variable() == nil {
+		j.errorf(init.Name.Pos(), "destructuring is not supported")
+	} else if ok {
+		id := init.Name.Variable()
+		j.declare(identifier(id.Value))
+		j.mark(init.Pos(), "")
+		j.write("let ")
+		j.mark(id.Pos(), id.Value)
+		j.write(identifier(id.Value) + " = ")
+		j.expression(init.Value)
+	}
+	j.write("; ")
+	j.condition(exp.StopCondition)
+	j.write("; ")
+	j.expression(exp.Increment)
+	j.write(") ")
+	j.scopedBlock(exp.Statements, body)
+	j.popScope()
+
+	if s.kind != discard {
+		j.value(s, func() { j.write(body.target) })
+	}
+}
+
+func (j *JS) whileStatement(exp *ast.WhileExpression, s sink) {
+	body := sink{kind: discard}
+	if s.kind != discard {
+		body = sink{kind: assign, target: j.temp()}
+		j.newline()
+		j.write("let " + body.target + " = null;")
+	}
+
+	j.newline()
+	j.label(exp.Label)
+	j.mark(exp.Pos(), "")
+	j.write("while (")
+	j.condition(exp.Condition)
+	j.write(") ")
+	j.scopedBlock(exp.Body, body)
+
+	if s.kind != discard {
+		j.value(s, func() { j.write(body.target) })
+	}
+}
+
+func (j *JS) label(label *ast.Identifier) {
+	if label != nil {
+		j.mark(label.Pos(), label.Value)
+		j.write(identifier(label.Value) + ": ")
+	}
+}
+
+// condition writes exp as a JavaScript boolean. Only false and null are
+// falsy in monkey, so anything that is not already a boolean goes through
+// the $truthy helper.
+func (j *JS) condition(exp ast.Expression) {
+	if isBoolean(exp) {
+		j.expression(exp)
+		return
+	}
+
+	j.write(j.helper("$truthy") + "(")
+	j.expression(exp)
+	j.write(")")
+}
+
+func isBoolean(exp ast.Expression) bool {
+	switch exp := exp.(type) {
+	case *ast.Boolean:
+		return true
+	case *ast.PrefixExpression:
+		return exp.Operator == "!"
+	case *ast.InfixExpression:
+		switch exp.Operator {
+		case "<", ">", "<=", ">=", "==", "!=", "&&", "||":
+			return true
+		}
+	}
+	return false
+}
+
+func (j *JS) expression(exp ast.Expression) {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		j.mark(exp.Pos(), exp.Value)
+		j.write(identifier(exp.Value))
+	case *ast.IntegerLiteral:
+		// The literal is written in decimal, monkey and JavaScript don't
+		// read a leading 0 the same way. Integers are BigInts so that the
+		// arithmetic doesn't lose precision past 2^53.
+		j.mark(exp.Pos(), "")
+		j.write(strconv.FormatInt(exp.Value, 10) + "n")
+	case *ast.BigIntegerLiteral:
+		j.mark(exp.Pos(), "")
+		j.write(exp.Value.String() + "n")
+	case *ast.Boolean:
+		j.mark(exp.Pos(), "")
+		j.write(exp.String())
+	case *ast.PrefixExpression:
+		j.mark(exp.Pos(), "")
+		j.prefixExpression(exp)
+	case *ast.InfixExpression:
+		j.infixExpression(exp)
+	case *ast.AssignExpression:
+		left, ok := exp.Left.(*ast.Identifier)
+		if !ok {
+			j.errorf(exp.Left.Pos(), "assignment to %s is not supported", exp.Left)
+			return
+		}
+		j.mark(left.Pos(), left.Value)
+		j.write(identifier(left.Value) + " = ")
+		j.expression(exp.Expression)
+	case *ast.FunctionLiteral:
+		j.mark(exp.Pos(), "")
+		j.parameters(exp.Parameters)
+		j.write(" => ")
+		j.functionBody(exp.Body)
+	case *ast.CallExpression:
+		j.operand(exp.Function)
+		j.mark(exp.Pos(), "")
+		j.write("(")
+		for i, arg := range exp.Arguments {
+			if i > 0 {
+				j.write(", ")
+			}
+			switch arg.(type) {
+			case *ast.SpreadExpression:
+				j.errorf(arg.Pos(), "spread arguments are not supported")
+				continue
+			case *ast.KeywordArgument:
+				j.errorf(arg.Pos(), "keyword arguments are not supported")
+				continue
+			}
+			j.expression(arg)
+		}
+		j.write(")")
+	case *ast.IfExpression:
+		j.ifExpression(exp)
+	case *ast.ForExpression:
+		j.iife(exp.Token, func() {
+			j.forStatement(exp, sink{kind: ret})
+		}, leaves(exp.Statements, loopLabel(exp.Label)))
+	case *ast.WhileExpression:
+		j.iife(exp.Token, func() {
+			j.whileStatement(exp, sink{kind: ret})
+		}, leaves(exp.Body, loopLabel(exp.Label)))
+	case *ast.StructLiteral:
+		j.errorf(exp.Pos(), "structs are not supported")
+	case nil:
+		j.write("null")
+	default:
+		j.errorf(exp.Pos(), "unsupported expression %T", exp)
+	}
+}
+
+// operand writes exp, adding parentheses when it is not a primary expression
+func (j *JS) operand(exp ast.Expression) {
+	switch exp.(type) {
+	case *ast.Identifier, *ast.IntegerLiteral, *ast.BigIntegerLiteral, *ast.Boolean, *ast.CallExpression:
+		j.expression(exp)
+	default:
+		j.write("(")
+		j.expression(exp)
+		j.write(")")
+	}
+}
+
+func (j *JS) prefixExpression(exp *ast.PrefixExpression) {
+	if exp.Operator == "!" && !isBoolean(exp.Right) {
+		j.write(j.helper("$not") + "(")
+		j.expression(exp.Right)
+		j.write(")")
+		return
+	}
+
+	j.write(exp.Operator)
+	j.operand(exp.Right)
+}
+
+func (j *JS) infixExpression(exp *ast.InfixExpression) {
+	if helper, ok := operatorHelpers[exp.Operator]; ok {
+		j.mark(exp.Pos(), "")
+		j.write(j.helper(helper) + "(")
+		j.expression(exp.Left)
+		j.write(", ")
+		j.expression(exp.Right)
+		j.write(")")
+		return
+	}
+
+	if exp.Operator == "&&" || exp.Operator == "||" {
+		// The operands are turned into booleans so that the result is a
+		// boolean and uses the truthiness of monkey
+		j.logicalOperand(exp.Left)
+		j.write(" ")
+		j.mark(exp.Pos(), "")
+		j.write(exp.Operator + " ")
+		j.logicalOperand(exp.Right)
+		return
+	}
+
+	operator := exp.Operator
+	switch operator {
+	case "==":
+		operator = "==="
+	case "!=":
+		operator = "!=="
+	}
+
+	j.operand(exp.Left)
+	j.write(" ")
+	j.mark(exp.Pos(), "")
+	j.write(operator + " ")
+	j.operand(exp.Right)
+}
+
+func (j *JS) logicalOperand(exp ast.Expression) {
+	if isBoolean(exp) {
+		j.operand(exp)
+		return
+	}
+	j.condition(exp)
+}
+
+// ifExpression writes an if used as a value. Simple branches become a
+// ternary, anything else is wrapped in an immediately invoked function.
+func (j *JS) ifExpression(exp *ast.IfExpression) {
+	consequence, ok := singleExpression(exp.Consequence)
+	alternative, altOk := singleExpression(exp.Alternative)
+
+	if !ok || !altOk {
+		jump := leaves(exp.Consequence, nil)
+		if jump == nil {
+			jump = leaves(exp.Alternative, nil)
+		}
+		j.iife(exp.Token, func() {
+			j.ifStatement(exp, sink{kind: ret})
+		}, jump)
+		return
+	}
+
+	j.mark(exp.Pos(), "")
+	j.condition(exp.Condition)
+	j.write(" ? ")
+	j.expression(consequence)
+	j.write(" : ")
+	j.expression(alternative)
+}
+
+// singleExpression returns the only expression of block, a missing block
+// has a nil expression which is written as null.
+func singleExpression(block *ast.BlockStatement) (ast.Expression, bool) {
+	if block == nil {
+		return nil, true
+	}
+
+	if len(block.Statements) != 1 {
+		return nil, false
+	}
+
+	stmt, ok := block.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		return nil, false
+	}
+
+	switch stmt.Expression.(type) {
+	case *ast.IfExpression, *ast.ForExpression, *ast.WhileExpression:
+		return nil, false
+	}
+
+	return stmt.Expression, true
+}
+
+// iife writes the code produced by emit in an immediately invoked function.
+// jump is the statement that would leave the function instead of the code
+// around it, if any.
+func (j *JS) iife(tok token.Token, emit func(), jump ast.Statement) {
+	if jump != nil {
+		j.errorf(tok.Pos(), "%s inside %s %s used as a value is not supported", jump.TokenLiteral(), article(tok.Literal), tok.Literal)
+		return
+	}
+
+	j.mark(tok.Pos(), "")
+	j.write("(() => {")
+	j.indent++
+	j.pushScope()
+	emit()
+	j.popScope()
+	j.indent--
+	j.newline()
+	j.write("})()")
+}
+
+func article(word string) string {
+	if strings.ContainsAny(word[:1], "aeiou") {
+		return "an"
+	}
+	return "a"
+}
+
+// leaves returns the first statement that makes block leave the code around
+// it other than by finishing: a return, or a break or continue to a loop
+// that is not in loops, the labels of the loops inside block
+func leaves(block *ast.BlockStatement, loops []string) ast.Statement {
+	if block == nil {
+		return nil
+	}
+
+	jumps := func(label *ast.Identifier) bool {
+		for _, l := range loops {
+			if label == nil || l == label.Value {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, stmt := range block.Statements {
+		switch stmt := stmt.(type) {
+		case *ast.ReturnStatement:
+			return stmt
+		case *ast.BreakStatement:
+			if jumps(stmt.Label) {
+				return stmt
+			}
+		case *ast.ContinueStatement:
+			if jumps(stmt.Label) {
+				return stmt
+			}
+		case *ast.ExpressionStatement:
+			var jump ast.Statement
+			switch exp := stmt.Expression.(type) {
+			case *ast.IfExpression:
+				jump = leaves(exp.Consequence, loops)
+				if jump == nil {
+					jump = leaves(exp.Alternative, loops)
+				}
+			case *ast.ForExpression:
+				jump = leaves(exp.Statements, append(loops, loopLabel(exp.Label)...))
+			case *ast.WhileExpression:
+				jump = leaves(exp.Body, append(loops, loopLabel(exp.Label)...))
+			}
+			if jump != nil {
+				return jump
+			}
+		}
+	}
+
+	return nil
+}
+
+// loopLabel returns the labels a break or continue can use to reach a loop,
+// the empty label is the innermost loop
+func loopLabel(label *ast.Identifier) []string {
+	if label == nil {
+		return []string{""}
+	}
+	return []string{"", label.Value}
+}
@@ -0,0 +1,203 @@
+package jsgen
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/rumpl/monkey-lang/lexer"
+	"github.com/rumpl/monkey-lang/parser"
+)
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = 1 + 2 * 3;", "let a = 1n + (2n * 3n);"},
+		{"let a = 1; let a = 2;", "let a = 1n;\na = 2n;"},
+		{"let a = 1 == 2;", "let a = 1n === 2n;"},
+		{"const a = 1;", "const a = 1n;"},
+		{"let a = 0755 + 0x1F + 0b11 + 1_000;", "let a = ((755n + 31n) + 3n) + 1000n;"},
+		{"let a = x % 2;", "let a = $mod(x, 2n);"},
+		{"let a = ~x & 1 | 2 ^ x << 3;", "let a = ((~x) & 1n) | (2n ^ ($shl(x, 3n)));"},
+		{"let a = x >> 1 + x >>> 2;", "let a = $ushr($shr(x, 1n + x), 2n);"},
+		{"let a = (x || 1 <= 2) && y;", "let a = ($truthy(x) || (1n <= 2n)) && $truthy(y);"},
+		{"let add = fn(a, b) { a + b };", "let add = (a, b) => {\n  return a + b;\n};"},
+		{"fn f() { return 1; }", "function f() {\n  return 1n;\n}"},
+		{"let a = if (1 < 2) { 1 } else { 2 };", "let a;\nif (1n < 2n) {\n  a = 1n;\n} else {\n  a = 2n;\n}"},
+		{"let b = fn(x) { if (x) { 1 } }(1);", "let b = ((x) => {\n  if ($truthy(x)) {\n    return 1n;\n  } else {\n    return null;\n  }\n})(1n);"},
+		{"let c = 1 + if (true) { 1 } else { 2 };", "let c = 1n + (true ? 1n : 2n);"},
+		{"let new = 1;", "let new$ = 1n;"},
+		{"while (x) { break; }", "while ($truthy(x)) {\n  break;\n}"},
+		{"l: while (true) { continue l; }", "l: while (true) {\n  continue l;\n}"},
+		{"let a = while (false) { 1 };", "let a;\nlet $1 = null;\nwhile (false) {\n  $1 = 1n;\n}\na = $1;"},
+		{"let a = 1 + for (let i = 0; i < 2; i = i + 1) { if (i == 1) { break; } i };", "let a = 1n + ((() => {\n  let $1 = null;\n  for (let i = 0n; i < 2n; i = i + 1n) {\n    if (i === 1n) {\n      break;\n    }\n    $1 = i;\n  }\n  return $1;\n})());"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			code := generate(t, tt.input)
+			if !strings.HasPrefix(code, tt.expected+"\n") {
+				t.Errorf("wrong code, expected\n%s\ngot\n%s", tt.expected, code)
+			}
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"return 1;", "1:1: return outside of a function is not supported"},
+		{"let f = fn() { 1 + if (true) { return 1; } else { 2 } };", "1:20: return inside an if used as a value is not supported"},
+		{"while (true) { 1 + if (true) { break; } else { 2 } }", "1:20: break inside an if used as a value is not supported"},
+		{"l: while (true) { let a = 1 + while (true) { continue l; }; }", "1:31: continue inside a while used as a value is not supported"},
+		{"let [a, b] = x;", "1:5: destructuring is not supported"},
+		{"let f = fn([a], b) { b };", "1:12: destructuring is not supported"},
+		{"for (let [i] = [0]; i < 1; i = i + 1) { i }", "1:10: destructuring is not supported"},
+		{"let f = fn(a, b = 1) { a + b };", "1:15: default parameters are not supported"},
+		{"let f = fn(a) { a }; f(a: 1);", "1:24: keyword arguments are not supported"},
+		{"let f = fn(a) { a[0] = 1 };", "1:18: assignment to (a[0]) is not supported"},
+		{"struct P { x }", "1:1: structs are not supported"},
+		{"impl P { fn get(self) { 1 } }", "1:1: methods are not supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p := parser.New(lexer.New(tt.input))
+			program := p.ParseProgram()
+			_, _, err := New(program, "test.monkey").Generate("test.js")
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			if err.Error() != tt.expected {
+				t.Errorf("wrong error, expected %q, got %q", tt.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestSourceMap(t *testing.T) {
+	input := `let a = 1;
+let b = a;`
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	_, sm, err := New(program, "test.monkey").Generate("test.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sm.Version != 3 || sm.File != "test.js" || sm.Sources[0] != "test.monkey" {
+		t.Errorf("wrong source map header %+v", sm)
+	}
+
+	if strings.Join(sm.Names, ",") != "a,b" {
+		t.Errorf("wrong names, got %v", sm.Names)
+	}
+
+	expected := "AAAA,IAAIA,IAAI;AACR,IAAIC,IAAID"
+	if sm.Mappings != expected {
+		t.Errorf("wrong mappings, expected %q, got %q", expected, sm.Mappings)
+	}
+}
+
+func TestEncodeVLQ(t *testing.T) {
+	tests := []struct {
+		value    int
+		expected string
+	}{
+		{0, "A"},
+		{1, "C"},
+		{-1, "D"},
+		{15, "e"},
+		{16, "gB"},
+		{-17, "jB"},
+		{1000, "w+B"},
+	}
+
+	for _, tt := range tests {
+		if got := encodeVLQ(tt.value); got != tt.expected {
+			t.Errorf("encodeVLQ(%d) expected %q, got %q", tt.value, tt.expected, got)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is not installed")
+	}
+
+	input := `
+fn fact(n) {
+	if (n < 2) { return 1; }
+	n * fact(n - 1)
+}
+let sum = for (let i = 0; i < 5; i = i + 1) { i * 10 / 3 };
+let r = if (!0) { 1 } else { fact(5) + sum };
+r;
+`
+	runNode(t, node, generate(t, input)+"console.log(String(r));\n", "133")
+}
+
+func TestRunIntegers(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is not installed")
+	}
+
+	// Integers past 2^53 must keep their precision, the arithmetic of the
+	// evaluator gives the expected values
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let r = 9007199254740992 + 1;", "9007199254740993"},
+		{"let r = 9007199254740993 * 3;", "27021597764222979"},
+		{"let r = 9223372036854775807 + 1;", "9223372036854775808"},
+		{"let r = 0 - 9007199254740993 - 2;", "-9007199254740995"},
+		{"let r = -7 / 2;", "-3"},
+		{"let r = -7 % 2;", "-1"},
+		{"let r = 1 << 40;", "1099511627776"},
+		{"let r = 1 << 64;", "18446744073709551616"},
+		{"let r = -16 >> 2;", "-4"},
+		{"let r = -1 >>> 60;", "15"},
+		{"let r = -1 >>> 0;", "-1"},
+		{"let r = ~5 ^ 3 & 6 | 8;", "-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			runNode(t, node, generate(t, tt.input)+"console.log(String(r));\n", tt.expected)
+		})
+	}
+}
+
+func runNode(t *testing.T, node string, code string, expected string) {
+	out, err := exec.Command(node, "-e", code).CombinedOutput()
+	if err != nil {
+		t.Fatalf("node failed: %s\n%s", err, out)
+	}
+
+	if strings.TrimSpace(string(out)) != expected {
+		t.Errorf("wrong output, expected %s, got %q", expected, out)
+	}
+}
+
+func generate(t *testing.T, input string) string {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	code, _, err := New(program, "test.monkey").Generate("test.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return code
+}
@@ -0,0 +1,93 @@
+package jsgen
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+const base64Chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// SourceMap is a version 3 source map
+type SourceMap struct {
+	Version  int      `json:"version"`
+	File     string   `json:"file"`
+	Sources  []string `json:"sources"`
+	Names    []string `json:"names"`
+	Mappings string   `json:"mappings"`
+}
+
+// JSON returns the source map serialized as json
+func (sm *SourceMap) JSON() ([]byte, error) {
+	return json.Marshal(sm)
+}
+
+// mapping links a position in the generated code to a position in the
+// monkey source, all zero based. name is -1 when the mapping has no name.
+type mapping struct {
+	genLine   int
+	genColumn int
+	srcLine   int
+	srcColumn int
+	name      int
+}
+
+// encodeMappings encodes the mappings, sorted by generated position, in
+// the base64 VLQ format of the "mappings" field.
+func encodeMappings(mappings []mapping) string {
+	var out bytes.Buffer
+
+	line := 0
+	prevGenColumn, prevSrcLine, prevSrcColumn, prevName := 0, 0, 0, 0
+
+	for i, m := range mappings {
+		if m.genLine != line {
+			for line < m.genLine {
+				out.WriteByte(';')
+				line++
+			}
+			prevGenColumn = 0
+		} else if i > 0 {
+			out.WriteByte(',')
+		}
+
+		out.WriteString(encodeVLQ(m.genColumn - prevGenColumn))
+		// There is only ever one source, so the source index delta is always 0
+		out.WriteString(encodeVLQ(0))
+		out.WriteString(encodeVLQ(m.srcLine - prevSrcLine))
+		out.WriteString(encodeVLQ(m.srcColumn - prevSrcColumn))
+
+		if m.name >= 0 {
+			out.WriteString(encodeVLQ(m.name - prevName))
+			prevName = m.name
+		}
+
+		prevGenColumn = m.genColumn
+		prevSrcLine = m.srcLine
+		prevSrcColumn = m.srcColumn
+	}
+
+	return out.String()
+}
+
+func encodeVLQ(value int) string {
+	var out bytes.Buffer
+
+	vlq := value << 1
+	if value < 0 {
+		vlq = (-value << 1) | 1
+	}
+
+	for {
+		digit := vlq & 31
+		vlq >>= 5
+		if vlq > 0 {
+			digit |= 32
+		}
+		out.WriteByte(base64Chars[digit])
+		if vlq == 0 {
+			break
+		}
+	}
+
+	return out.String()
+}
@@ -1,6 +1,8 @@
 package lexer
 
 import (
+	"strings"
+
 	"github.com/rumpl/monkey-lang/token"
 )
 
@@ -10,12 +12,15 @@ type Lexer struct {
 	position     int  // current position in input (points to current char)
 	readPosition int  // current reading position (after current char)
 	ch           byte // current char under examination
+	line         int  // line of the current char, starting at 1
+	lineStart    int  // position of the first char of the current line
 }
 
 // New returns a new lexer
 func New(input string) *Lexer {
 	l := &Lexer{
 		input: input,
+		line:  1,
 	}
 
 	// Setup the lexer so that ch, position and readPosition are initialized
@@ -25,6 +30,11 @@ func New(input string) *Lexer {
 }
 
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.lineStart = l.readPosition
+	}
+
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -53,20 +63,87 @@ func (l *Lexer) readIdentifier() string {
 }
 
 func (l *Lexer) isLetter() bool {
-	return 'a' <= l.ch && l.ch <= 'z' || 'A' <= l.ch && l.ch <= 'Z' && l.ch == '_'
+	return isLetter(l.ch)
 }
 
-func (l *Lexer) readNumber() string {
+func isLetter(ch byte) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+}
+
+// readNumber reads an integer or a float. Integers are decimal or have a
+// base prefix: 0x1F, 0o17, 0b1010. A float has a fractional part, an exponent
+// or both: 3.14, .5, 1e-9. Underscores can separate digits: 1_000_000. A
+// malformed number is returned as a single ILLEGAL token.
+func (l *Lexer) readNumber() (string, token.Type) {
 	position := l.position
+	prefixed := l.ch == '0' && strings.IndexByte("xXoObB", l.peekChar()) >= 0
+	fraction := false
 
-	for l.isDigit() {
-		l.readChar()
+	for {
+		switch {
+		case (l.ch == 'e' || l.ch == 'E') && !prefixed:
+			l.readChar()
+			if l.ch == '+' || l.ch == '-' {
+				l.readChar()
+			}
+		case l.ch == '.' && !fraction && isDigit(l.peekChar()):
+			// The fraction of a prefixed integer is read too, NumberError
+			// reports it
+			fraction = true
+			l.readChar()
+		case isDigit(l.ch) || isLetter(l.ch):
+			l.readChar()
+		default:
+			literal := l.input[position:l.position]
+			if NumberError(literal) != "" {
+				return literal, token.ILLEGAL
+			}
+			if !prefixed && strings.ContainsAny(literal, ".eE") {
+				return literal, token.FLOAT
+			}
+			return literal, token.INT
+		}
 	}
-	return l.input[position:l.position]
 }
 
 func (l *Lexer) isDigit() bool {
-	return '0' <= l.ch && l.ch <= '9'
+	return isDigit(l.ch)
+}
+
+func isDigit(ch byte) bool {
+	return '0' <= ch && ch <= '9'
+}
+
+// readString reads a string literal and returns its value, escape sequences
+// are replaced by the character they stand for
+func (l *Lexer) readString() string {
+	var out strings.Builder
+
+	for {
+		l.readChar()
+		if l.ch == '"' || l.ch == 0 {
+			break
+		}
+
+		if l.ch == '\\' {
+			l.readChar()
+			switch l.ch {
+			case 'n':
+				out.WriteByte('\n')
+			case 't':
+				out.WriteByte('\t')
+			case 0:
+				return out.String()
+			default:
+				out.WriteByte(l.ch)
+			}
+			continue
+		}
+
+		out.WriteByte(l.ch)
+	}
+
+	return out.String()
 }
 
 func (l *Lexer) skipWhitespace() {
@@ -77,30 +154,62 @@ func (l *Lexer) skipWhitespace() {
 
 // NextToken reads and returns the next valid token
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
-
 	l.skipWhitespace()
 
+	line, column := l.line, l.position-l.lineStart+1
+
+	tok := l.readToken()
+	tok.Line = line
+	tok.Column = column
+
+	return tok
+}
+
+func (l *Lexer) readToken() token.Token {
+	var tok token.Token
+
 	switch l.ch {
 	case '=':
-		if l.peekChar() == '=' {
-			ch := l.ch
+		if l.peekChar() == '>' {
+			tok = l.twoCharToken('>', token.FATARROW, token.ASSIGN)
+		} else {
+			tok = l.twoCharToken('=', token.EQ, token.ASSIGN)
+		}
+	case '.':
+		if isDigit(l.peekChar()) {
+			tok.Literal, tok.Type = l.readNumber()
+			return tok
+		}
+		if strings.HasPrefix(l.input[l.position:], "...") {
 			l.readChar()
-			literal := string(ch) + string(l.ch)
-			tok = token.Token{Type: token.EQ, Literal: literal}
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
 		} else {
-			tok = newToken(token.ASSIGN, l.ch)
+			tok = newToken(token.DOT, l.ch)
 		}
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		if l.peekChar() == '+' {
+			tok = l.twoCharToken('+', token.INCREMENT, token.PLUS)
+		} else {
+			tok = l.twoCharToken('=', token.PLUS_ASSIGN, token.PLUS)
+		}
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
+	case ':':
+		tok = newToken(token.COLON, l.ch)
 	case '(':
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
 		tok = newToken(token.RPAREN, l.ch)
+	case '"':
+		tok.Type = token.STRING
+		tok.Literal = l.readString()
+	case '[':
+		tok = newToken(token.LBRACKET, l.ch)
+	case ']':
+		tok = newToken(token.RBRACKET, l.ch)
 	case '{':
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
@@ -115,15 +224,45 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.BANG, l.ch)
 		}
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		switch l.peekChar() {
+		case '>':
+			tok = l.twoCharToken('>', token.ARROW, token.MINUS)
+		case '-':
+			tok = l.twoCharToken('-', token.DECREMENT, token.MINUS)
+		default:
+			tok = l.twoCharToken('=', token.MINUS_ASSIGN, token.MINUS)
+		}
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		tok = l.twoCharToken('=', token.SLASH_ASSIGN, token.SLASH)
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		tok = l.twoCharToken('=', token.ASTERISK_ASSIGN, token.ASTERISK)
+	case '%':
+		tok = l.twoCharToken('=', token.PERCENT_ASSIGN, token.PERCENT)
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		if l.peekChar() == '<' {
+			tok = l.twoCharToken('<', token.SHL, token.LT)
+		} else {
+			tok = l.twoCharToken('=', token.LTE, token.LT)
+		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		if l.peekChar() != '>' {
+			tok = l.twoCharToken('=', token.GTE, token.GT)
+			break
+		}
+
+		l.readChar()
+		tok = l.twoCharToken('>', token.USHR, token.SHR)
+		tok.Literal = ">" + tok.Literal
+	case '&':
+		tok = l.twoCharToken('&', token.AND, token.AMPERSAND)
+	case '|':
+		tok = l.twoCharToken('|', token.OR, token.PIPE)
+	case '^':
+		tok = newToken(token.CARET, l.ch)
+	case '~':
+		tok = newToken(token.TILDE, l.ch)
+	case '?':
+		tok = newToken(token.QUESTION, l.ch)
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
@@ -133,8 +272,7 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Type = token.LookupIdent(tok.Literal)
 			return tok
 		} else if l.isDigit() {
-			tok.Literal = l.readNumber()
-			tok.Type = token.INT
+			tok.Literal, tok.Type = l.readNumber()
 			return tok
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
@@ -147,6 +285,18 @@ func (l *Lexer) NextToken() token.Token {
 	return tok
 }
 
+// twoCharToken returns a token of type two when the current char is followed
+// by next and a token of type one otherwise
+func (l *Lexer) twoCharToken(next byte, two token.Type, one token.Type) token.Token {
+	if l.peekChar() != next {
+		return newToken(one, l.ch)
+	}
+
+	ch := l.ch
+	l.readChar()
+	return token.Token{Type: two, Literal: string(ch) + string(l.ch)}
+}
+
 func newToken(tokenType token.Type, ch byte) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
@@ -23,6 +23,19 @@ let result = add(five, ten);
 10 == 10;
 10 != 9;
 for (let i = 0; i < 10; i = i + 1) { i }
+fn(a: int) -> int
+"foo bar" "a\"b\n"
+[1, 2]
+for (k, v in h)
+a <= b >= c && d || e % f
+a & b | c ^ ~d << 1 >> 2 >>> 3 > 4
+3.14 .5 1e-9 2E3 1e x
+MAX_VALUE _tmp
+f(x)?
+match (x) { [a, ...r] => a }
+i++ j-- a += 1 -= *= /= %= -> - -1
+p.x
+struct P { x } impl
 `
 
 	tests := []struct {
@@ -120,6 +133,107 @@ for (let i = 0; i < 10; i = i + 1) { i }
 		{token.LBRACE, "{"},
 		{token.IDENT, "i"},
 		{token.RBRACE, "}"},
+		{token.FUNCTION, "fn"},
+		{token.LPAREN, "("},
+		{token.IDENT, "a"},
+		{token.COLON, ":"},
+		{token.IDENT, "int"},
+		{token.RPAREN, ")"},
+		{token.ARROW, "->"},
+		{token.IDENT, "int"},
+		{token.STRING, "foo bar"},
+		{token.STRING, "a\"b\n"},
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.COMMA, ","},
+		{token.INT, "2"},
+		{token.RBRACKET, "]"},
+		{token.FOR, "for"},
+		{token.LPAREN, "("},
+		{token.IDENT, "k"},
+		{token.COMMA, ","},
+		{token.IDENT, "v"},
+		{token.IN, "in"},
+		{token.IDENT, "h"},
+		{token.RPAREN, ")"},
+		{token.IDENT, "a"},
+		{token.LTE, "<="},
+		{token.IDENT, "b"},
+		{token.GTE, ">="},
+		{token.IDENT, "c"},
+		{token.AND, "&&"},
+		{token.IDENT, "d"},
+		{token.OR, "||"},
+		{token.IDENT, "e"},
+		{token.PERCENT, "%"},
+		{token.IDENT, "f"},
+		{token.IDENT, "a"},
+		{token.AMPERSAND, "&"},
+		{token.IDENT, "b"},
+		{token.PIPE, "|"},
+		{token.IDENT, "c"},
+		{token.CARET, "^"},
+		{token.TILDE, "~"},
+		{token.IDENT, "d"},
+		{token.SHL, "<<"},
+		{token.INT, "1"},
+		{token.SHR, ">>"},
+		{token.INT, "2"},
+		{token.USHR, ">>>"},
+		{token.INT, "3"},
+		{token.GT, ">"},
+		{token.INT, "4"},
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, ".5"},
+		{token.FLOAT, "1e-9"},
+		{token.FLOAT, "2E3"},
+		{token.ILLEGAL, "1e"},
+		{token.IDENT, "x"},
+		{token.IDENT, "MAX_VALUE"},
+		{token.IDENT, "_tmp"},
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.QUESTION, "?"},
+		{token.MATCH, "match"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.LBRACKET, "["},
+		{token.IDENT, "a"},
+		{token.COMMA, ","},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "r"},
+		{token.RBRACKET, "]"},
+		{token.FATARROW, "=>"},
+		{token.IDENT, "a"},
+		{token.RBRACE, "}"},
+		{token.IDENT, "i"},
+		{token.INCREMENT, "++"},
+		{token.IDENT, "j"},
+		{token.DECREMENT, "--"},
+		{token.IDENT, "a"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.INT, "1"},
+		{token.MINUS_ASSIGN, "-="},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.SLASH_ASSIGN, "/="},
+		{token.PERCENT_ASSIGN, "%="},
+		{token.ARROW, "->"},
+		{token.MINUS, "-"},
+		{token.MINUS, "-"},
+		{token.INT, "1"},
+		{token.IDENT, "p"},
+		{token.DOT, "."},
+		{token.IDENT, "x"},
+		{token.STRUCT, "struct"},
+		{token.IDENT, "P"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "x"},
+		{token.RBRACE, "}"},
+		{token.IMPL, "impl"},
 		{token.EOF, ""},
 	}
 
@@ -137,3 +251,81 @@ for (let i = 0; i < 10; i = i + 1) { i }
 		}
 	}
 }
+
+func TestTokenPosition(t *testing.T) {
+	input := `let a = 5;
+  a + 10
+`
+
+	tests := []struct {
+		expectedType   token.Type
+		expectedLine   int
+		expectedColumn int
+	}{
+		{token.LET, 1, 1},
+		{token.IDENT, 1, 5},
+		{token.ASSIGN, 1, 7},
+		{token.INT, 1, 9},
+		{token.SEMICOLON, 1, 10},
+		{token.IDENT, 2, 3},
+		{token.PLUS, 2, 5},
+		{token.INT, 2, 7},
+		{token.EOF, 3, 1},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. Expected=%q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - position wrong. Expected=%d:%d, got %d:%d", i, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.Type
+		expectedLiteral string
+		expectedError   string
+	}{
+		{"0x1F", token.INT, "0x1F", ""},
+		{"0XdeadBEEF", token.INT, "0XdeadBEEF", ""},
+		{"0o17", token.INT, "0o17", ""},
+		{"0b1010", token.INT, "0b1010", ""},
+		{"1_000_000", token.INT, "1_000_000", ""},
+		{"0x_FF_FF", token.INT, "0x_FF_FF", ""},
+		{"1_000.000_5e1_0", token.FLOAT, "1_000.000_5e1_0", ""},
+		{"0x", token.ILLEGAL, "0x", "hexadecimal literal has no digits"},
+		{"0b_", token.ILLEGAL, "0b_", "binary literal has no digits"},
+		{"12abc", token.ILLEGAL, "12abc", "invalid digit 'a' in decimal literal"},
+		{"0o19", token.ILLEGAL, "0o19", "invalid digit '9' in octal literal"},
+		{"0b102", token.ILLEGAL, "0b102", "invalid digit '2' in binary literal"},
+		{"0xFG", token.ILLEGAL, "0xFG", "invalid digit 'G' in hexadecimal literal"},
+		{"1__0", token.ILLEGAL, "1__0", "'_' must separate successive digits"},
+		{"100_", token.ILLEGAL, "100_", "'_' must separate successive digits"},
+		{"1e+", token.ILLEGAL, "1e+", "exponent has no digits"},
+		{"1e5e3", token.ILLEGAL, "1e5e3", "invalid digit 'e' in decimal literal"},
+		{"0x1F.5", token.ILLEGAL, "0x1F.5", "hexadecimal literal cannot have a fractional part"},
+		{"0b1.1", token.ILLEGAL, "0b1.1", "binary literal cannot have a fractional part"},
+		{"0o7.0e1", token.ILLEGAL, "0o7.0e1", "octal literal cannot have a fractional part"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			tok := New(tt.input + ";").NextToken()
+			if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+				t.Fatalf("expected %s %q, got %s %q", tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+			}
+
+			if err := NumberError(tt.input); err != tt.expectedError {
+				t.Errorf("expected error %q, got %q", tt.expectedError, err)
+			}
+		})
+	}
+}
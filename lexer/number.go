@@ -0,0 +1,72 @@
+package lexer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// bases maps the letter of a base prefix to the name of the base and its
+// digits
+var bases = map[byte]struct {
+	name   string
+	digits string
+}{
+	'x': {"hexadecimal", "0123456789abcdefABCDEF"},
+	'o': {"octal", "01234567"},
+	'b': {"binary", "01"},
+}
+
+// NumberError tells what is wrong with a number literal, it returns an empty
+// string for a well-formed one
+func NumberError(literal string) string {
+	digits := "0123456789"
+	name := "decimal"
+	body := literal
+
+	if len(literal) > 1 && literal[0] == '0' {
+		if base, ok := bases[lower(literal[1])]; ok {
+			name, digits, body = base.name, base.digits, literal[2:]
+			if strings.Trim(body, "_") == "" {
+				return fmt.Sprintf("%s literal has no digits", name)
+			}
+			// An underscore can follow the prefix: 0x_FF
+			body = strings.TrimPrefix(body, "_")
+		}
+	}
+
+	isDigit := func(i int) bool {
+		return i >= 0 && i < len(body) && strings.IndexByte(digits, body[i]) >= 0
+	}
+
+	exponent := false
+	for i := 0; i < len(body); i++ {
+		switch ch := body[i]; {
+		case ch == '_':
+			if !isDigit(i-1) || !isDigit(i+1) {
+				return "'_' must separate successive digits"
+			}
+		case name == "decimal" && (ch == 'e' || ch == 'E') && !exponent:
+			exponent = true
+			if i+1 < len(body) && (body[i+1] == '+' || body[i+1] == '-') {
+				i++
+			}
+			if !isDigit(i + 1) {
+				return "exponent has no digits"
+			}
+		case name == "decimal" && ch == '.' && !exponent:
+		case ch == '.' && name != "decimal":
+			return fmt.Sprintf("%s literal cannot have a fractional part", name)
+		case strings.IndexByte(digits, ch) < 0:
+			return fmt.Sprintf("invalid digit %q in %s literal", ch, name)
+		}
+	}
+
+	return ""
+}
+
+func lower(ch byte) byte {
+	if 'A' <= ch && ch <= 'Z' {
+		return ch + 'a' - 'A'
+	}
+	return ch
+}
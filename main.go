@@ -1,36 +1,100 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/rumpl/monkey-lang/ast"
 	"github.com/rumpl/monkey-lang/codegen"
+	"github.com/rumpl/monkey-lang/difftest"
+	"github.com/rumpl/monkey-lang/eval"
+	"github.com/rumpl/monkey-lang/jsgen"
 	"github.com/rumpl/monkey-lang/lexer"
 	"github.com/rumpl/monkey-lang/object"
+	"github.com/rumpl/monkey-lang/optimize"
 	"github.com/rumpl/monkey-lang/parser"
+	"github.com/rumpl/monkey-lang/resolver"
+	"github.com/rumpl/monkey-lang/types"
 )
 
 func main() {
-	compile("./monkey/hello.monkey")
-	// fmt.Println("This is the Monkey programming language!")
-	// repl.Start(os.Stdin, os.Stdout)
+	args := os.Args[1:]
+	if len(args) == 0 {
+		compile("./monkey/hello.monkey")
+		// fmt.Println("This is the Monkey programming language!")
+		// repl.Start(os.Stdin, os.Stdout)
+		return
+	}
+
+	switch args[0] {
+	case "run":
+		strict := len(args) == 3 && args[1] == "--strict-overflow"
+		if len(args) != 2 && !strict {
+			fmt.Println("usage: monkey run [--strict-overflow] <file.monkey>")
+			os.Exit(1)
+		}
+		if err := run(args[len(args)-1], strict); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	case "js":
+		if len(args) != 2 {
+			fmt.Println("usage: monkey js <file.monkey>")
+			os.Exit(1)
+		}
+		if err := transpile(args[1]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	case "check":
+		infer := len(args) == 3 && args[1] == "--infer"
+		if len(args) != 2 && !infer {
+			fmt.Println("usage: monkey check [--infer] <file.monkey>")
+			os.Exit(1)
+		}
+		ok, err := check(args[len(args)-1], infer)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if !ok {
+			os.Exit(1)
+		}
+	case "difftest":
+		paths := args[1:]
+		if len(paths) == 0 {
+			paths = []string{"./difftest/testdata"}
+		}
+		ok, err := diff(paths)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if !ok {
+			os.Exit(1)
+		}
+	default:
+		fmt.Printf("unknown command %q\n", args[0])
+		os.Exit(1)
+	}
 }
 
 func compile(file string) {
 	env := object.NewEnvironment()
 
-	code, err := ioutil.ReadFile(file)
+	program, err := parseFile(file)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
 
-	l := lexer.New(string(code))
-	p := parser.New(l)
-
-	program := p.ParseProgram()
-	if len(p.Errors()) != 0 {
-		printParserErrors(p.Errors())
+	program, err = optimizeProgram(program)
+	if err != nil {
+		fmt.Println(err)
 		return
 	}
 
@@ -42,6 +106,163 @@ func compile(file string) {
 	}
 }
 
+// run evaluates file with the interpreter, its main function is called when
+// there is one. Integers that overflow become big integers unless strict is
+// set, the overflow is an error then.
+func run(file string, strict bool) error {
+	program, err := parseFile(file)
+	if err != nil {
+		return err
+	}
+
+	env := object.NewEnvironment()
+	if strict {
+		env.SetOverflowMode(object.ErrorOnOverflow)
+	}
+
+	result := eval.Run(program, env)
+	if errObj, ok := result.(*object.Error); ok {
+		if errObj.Trace != "" {
+			fmt.Fprintln(os.Stderr, errObj.Trace)
+		}
+		msg := errObj.Message
+		for _, call := range errObj.Stack {
+			msg += "\n\tat " + call
+		}
+		return errors.New(msg)
+	}
+
+	if result != nil {
+		fmt.Println(result.Inspect())
+	}
+
+	return nil
+}
+
+// transpile writes the JavaScript translation of file and its source map
+// next to it
+func transpile(file string) error {
+	program, err := parseFile(file)
+	if err != nil {
+		return err
+	}
+
+	program, err = optimizeProgram(program)
+	if err != nil {
+		return err
+	}
+
+	out := strings.TrimSuffix(file, filepath.Ext(file)) + ".js"
+
+	code, sourceMap, err := jsgen.New(program, filepath.Base(file)).Generate(filepath.Base(out))
+	if err != nil {
+		return err
+	}
+
+	sm, err := sourceMap.JSON()
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(out, []byte(code), 0644); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(out+".map", sm, 0644)
+}
+
+// check reports the problems found in file without running it, it returns
+// false when there are errors. With infer the types of the program are
+// inferred without looking at its annotations and printed.
+func check(file string, infer bool) (bool, error) {
+	program, err := parseFile(file)
+	if err != nil {
+		return false, err
+	}
+
+	ok := true
+	for _, d := range resolver.Resolve(program) {
+		if d.Severity == resolver.Error {
+			ok = false
+		}
+		fmt.Printf("%s:%s\n", file, d)
+	}
+
+	var typeErrors []types.Error
+	if infer {
+		var signatures []types.Signature
+		signatures, typeErrors = types.Infer(program)
+		for _, s := range signatures {
+			fmt.Printf("%s:%s\n", file, s)
+		}
+	} else {
+		_, typeErrors = types.Check(program)
+	}
+
+	for _, e := range typeErrors {
+		ok = false
+		fmt.Printf("%s:%s: error: %s\n", file, e.Pos, e.Message)
+	}
+
+	return ok, nil
+}
+
+// diff runs the programs found in paths with every backend and reports
+// whether they all agreed
+func diff(paths []string) (bool, error) {
+	files, err := difftest.Files(paths)
+	if err != nil {
+		return false, err
+	}
+
+	ok := true
+	for _, file := range files {
+		report, err := difftest.Run(file)
+		if err != nil {
+			return false, err
+		}
+
+		if report.Mismatch() {
+			ok = false
+			fmt.Println("MISMATCH", report)
+		} else {
+			fmt.Println("ok", report)
+		}
+	}
+
+	return ok, nil
+}
+
+// optimizeProgram runs the optimization passes selected by the MONKEY_OPT
+// environment variable, every pass runs when it is not set. Setting it to
+// "none" disables optimizations, which is handy when debugging a backend.
+func optimizeProgram(program *ast.Program) (*ast.Program, error) {
+	pipeline, err := optimize.Parse(os.Getenv("MONKEY_OPT"))
+	if err != nil {
+		return nil, err
+	}
+
+	return pipeline.Run(program), nil
+}
+
+func parseFile(file string) (*ast.Program, error) {
+	code, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	l := lexer.New(string(code))
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParserErrors(p.Errors())
+		return nil, errors.New("parsing failed")
+	}
+
+	return program, nil
+}
+
 func printParserErrors(errors []string) {
 	for _, msg := range errors {
 		fmt.Println("\t" + msg)
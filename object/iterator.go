@@ -0,0 +1,109 @@
+package object
+
+import "fmt"
+
+// Iterator walks the elements of an iterable object
+type Iterator interface {
+	// Next returns the next key and value, ok is false once every element
+	// has been returned
+	Next() (key Object, value Object, ok bool)
+}
+
+// Iterable is implemented by the objects a for in loop can walk
+type Iterable interface {
+	Object
+	Iterate() Iterator
+}
+
+// Range is the integers from Start up to End, excluded, going by Step. A
+// negative Step counts down.
+type Range struct {
+	Start int64
+	End   int64
+	Step  int64
+}
+
+func (r *Range) Type() Type {
+	return RangeObj
+}
+
+func (r *Range) Inspect() string {
+	return fmt.Sprintf("range(%d, %d, %d)", r.Start, r.End, r.Step)
+}
+
+// Iterate returns the position of each integer of the range with the
+// integer
+func (r *Range) Iterate() Iterator {
+	return &rangeIterator{r: r, current: r.Start}
+}
+
+type rangeIterator struct {
+	r       *Range
+	current int64
+	index   int64
+	done    bool
+}
+
+func (it *rangeIterator) Next() (Object, Object, bool) {
+	if it.done || (it.r.Step > 0 && it.current >= it.r.End) || (it.r.Step < 0 && it.current <= it.r.End) {
+		return nil, nil, false
+	}
+
+	key, value := &Integer{Value: it.index}, &Integer{Value: it.current}
+	it.index++
+	// Past the largest or the smallest integer there is nothing left before
+	// End, wrapping around would start the range again
+	if Overflows("+", it.current, it.r.Step) {
+		it.done = true
+	}
+	it.current += it.r.Step
+
+	return key, value, true
+}
+
+// Iterate returns the index of each element of the array with the element
+func (a *Array) Iterate() Iterator {
+	i := 0
+	return iteratorFunc(func() (Object, Object, bool) {
+		if i >= len(a.Elements) {
+			return nil, nil, false
+		}
+		i++
+		return &Integer{Value: int64(i - 1)}, a.Elements[i-1], true
+	})
+}
+
+// Iterate returns the index of each character of the string with the
+// character
+func (s *String) Iterate() Iterator {
+	chars := []rune(s.Value)
+	i := 0
+	return iteratorFunc(func() (Object, Object, bool) {
+		if i >= len(chars) {
+			return nil, nil, false
+		}
+		i++
+		return &Integer{Value: int64(i - 1)}, &String{Value: string(chars[i-1])}, true
+	})
+}
+
+// Iterate returns the keys of the hash with their value in the order they
+// were added
+func (h *Hash) Iterate() Iterator {
+	keys := append([]HashKey{}, h.keys...)
+	i := 0
+	return iteratorFunc(func() (Object, Object, bool) {
+		if i >= len(keys) {
+			return nil, nil, false
+		}
+		pair := h.Pairs[keys[i]]
+		i++
+		return pair.Key, pair.Value, true
+	})
+}
+
+type iteratorFunc func() (Object, Object, bool)
+
+func (f iteratorFunc) Next() (Object, Object, bool) {
+	return f()
+}
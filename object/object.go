@@ -3,6 +3,9 @@ package object
 import (
 	"bytes"
 	"fmt"
+	"hash/fnv"
+	"math"
+	"math/big"
 	"strings"
 
 	"github.com/rumpl/monkey-lang/ast"
@@ -12,11 +15,24 @@ type Type string
 
 const (
 	IntegerObj     = "INTEGER"
+	BigIntObj      = "BIGINT"
+	FloatObj       = "FLOAT"
 	BooleanObj     = "BOOLEAN"
 	NullObj        = "NULL"
 	ReturnValueObj = "RETURN_VALUE"
+	BreakObj       = "BREAK"
+	ContinueObj    = "CONTINUE"
 	ErrorObj       = "ERROR"
+	ExceptionObj   = "EXCEPTION"
 	FunctionObj    = "FUNCTION"
+	BuiltinObj     = "BUILTIN"
+	StringObj      = "STRING"
+	ArrayObj       = "ARRAY"
+	HashObj        = "HASH"
+	RangeObj       = "RANGE"
+	ResultObj      = "RESULT"
+	StructTypeObj  = "STRUCT_TYPE"
+	StructObj      = "STRUCT"
 )
 
 type Object interface {
@@ -36,6 +52,50 @@ func (i *Integer) Inspect() string {
 	return fmt.Sprintf("%d", i.Value)
 }
 
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+// BigInt is an integer too large for an Integer, the result of an operation
+// on big integers is an Integer again when it fits in one
+type BigInt struct {
+	Value *big.Int
+}
+
+func (b *BigInt) Type() Type {
+	return BigIntObj
+}
+
+func (b *BigInt) Inspect() string {
+	return b.Value.String()
+}
+
+func (b *BigInt) HashKey() HashKey {
+	h := fnv.New64a()
+	_, _ = h.Write(b.Value.Bytes())
+	value := h.Sum64()
+	if b.Value.Sign() < 0 {
+		value = ^value
+	}
+	return HashKey{Type: b.Type(), Value: value}
+}
+
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() Type {
+	return FloatObj
+}
+
+func (f *Float) Inspect() string {
+	return ast.FormatFloat(f.Value)
+}
+
+func (f *Float) HashKey() HashKey {
+	return HashKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -48,6 +108,14 @@ func (b *Boolean) Inspect() string {
 	return fmt.Sprintf("%t", b.Value)
 }
 
+func (b *Boolean) HashKey() HashKey {
+	var value uint64
+	if b.Value {
+		value = 1
+	}
+	return HashKey{Type: b.Type(), Value: value}
+}
+
 type Null struct {
 }
 
@@ -71,8 +139,72 @@ func (rv *ReturnValue) Inspect() string {
 	return rv.Value.Inspect()
 }
 
+// Break stops the loop named Label, the innermost loop when Label is empty.
+// Like ReturnValue it is passed up until it reaches that loop.
+type Break struct {
+	Label string
+}
+
+func (b *Break) Type() Type {
+	return BreakObj
+}
+
+func (b *Break) Inspect() string {
+	return "break"
+}
+
+// Continue starts the next iteration of the loop named Label, the innermost
+// loop when Label is empty
+type Continue struct {
+	Label string
+}
+
+func (c *Continue) Type() Type {
+	return ContinueObj
+}
+
+func (c *Continue) Inspect() string {
+	return "continue"
+}
+
+// Result is the value of ok(v) when Ok is set and of err(e) otherwise, unlike
+// an Error it doesn't abort the evaluation and is handled by the program
+type Result struct {
+	Ok    bool
+	Value Object
+}
+
+func (r *Result) Type() Type {
+	return ResultObj
+}
+
+func (r *Result) Inspect() string {
+	if r.Ok {
+		return "ok(" + r.Value.Inspect() + ")"
+	}
+	return "err(" + r.Value.Inspect() + ")"
+}
+
+// The kinds of the errors raised by the interpreter, a thrown value that is
+// not an exception becomes an error of kind GenericError
+const (
+	GenericError    = "Error"
+	TypeError       = "TypeError"
+	NameError       = "NameError"
+	ArithmeticError = "ArithmeticError"
+	InternalError   = "InternalError"
+)
+
+// Error aborts the evaluation up to the closest try expression, or up to the
+// program when there is none
 type Error struct {
+	Kind    string
 	Message string
+	// Stack lists the calls the error went through, innermost first
+	Stack []string
+	// Trace is the Go stack trace of an internal error of the interpreter,
+	// it is empty for the errors of the program
+	Trace string
 }
 
 func (e *Error) Type() Type {
@@ -83,6 +215,61 @@ func (e *Error) Inspect() string {
 	return "ERROR: " + e.Message
 }
 
+// Exception is an error caught by a catch clause. Unlike the Error it holds,
+// it is a value the program can pass around, inspect and throw again.
+type Exception struct {
+	Error *Error
+}
+
+func (e *Exception) Type() Type {
+	return ExceptionObj
+}
+
+func (e *Exception) Inspect() string {
+	return e.Error.Kind + ": " + e.Error.Message
+}
+
+// OverflowMode tells what integer arithmetic does with a result that doesn't
+// fit in an Integer
+type OverflowMode int
+
+const (
+	// PromoteOnOverflow turns the result into a BigInt
+	PromoteOnOverflow OverflowMode = iota
+	// ErrorOnOverflow makes the operation fail with an error
+	ErrorOnOverflow
+)
+
+// Overflows tells whether the integer operation a operator b has a result
+// that doesn't fit in 64 bits
+func Overflows(operator string, a, b int64) bool {
+	switch operator {
+	case "+":
+		r := a + b
+		return (a > 0 && b > 0 && r < 0) || (a < 0 && b < 0 && r >= 0)
+	case "-":
+		r := a - b
+		return (a >= 0 && b < 0 && r < 0) || (a < 0 && b > 0 && r >= 0)
+	case "*":
+		if a == 0 || b == 0 {
+			return false
+		}
+		if (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+			return true
+		}
+		return (a*b)/b != a
+	case "/":
+		return a == math.MinInt64 && b == -1
+	case "<<":
+		if b < 0 || a == 0 {
+			return false
+		}
+		return b >= 64 || (a<<uint64(b))>>uint64(b) != a
+	}
+
+	return false
+}
+
 func NewEnvironment() *Environment {
 	s := make(map[string]Object)
 	return &Environment{store: s, outer: nil}
@@ -96,7 +283,31 @@ func NewEnclosedEnvironment(outer *Environment) *Environment {
 
 type Environment struct {
 	store map[string]Object
-	outer *Environment
+	// constants holds the names of the store bound by a const statement
+	constants map[string]bool
+	outer     *Environment
+	// overflow is only used in the outermost environment, it is shared by
+	// the whole interpreter
+	overflow OverflowMode
+}
+
+// SetOverflowMode selects what the integer arithmetic of the interpreter
+// using the environment does on overflow
+func (e *Environment) SetOverflowMode(mode OverflowMode) {
+	e.root().overflow = mode
+}
+
+// OverflowMode returns what the integer arithmetic of the interpreter using
+// the environment does on overflow
+func (e *Environment) OverflowMode() OverflowMode {
+	return e.root().overflow
+}
+
+func (e *Environment) root() *Environment {
+	for e.outer != nil {
+		e = e.outer
+	}
+	return e
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
@@ -109,9 +320,48 @@ func (e *Environment) Get(name string) (Object, bool) {
 
 func (e *Environment) Set(name string, val Object) Object {
 	e.store[name] = val
+	delete(e.constants, name)
 	return val
 }
 
+// MakeConstant keeps the variable name of the store from being assigned to,
+// until it is declared again
+func (e *Environment) MakeConstant(name string) {
+	if e.constants == nil {
+		e.constants = map[string]bool{}
+	}
+	e.constants[name] = true
+}
+
+// Constant reports whether the variable name is a constant in the
+// environment that defines it
+func (e *Environment) Constant(name string) bool {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			return env.constants[name]
+		}
+	}
+	return false
+}
+
+// LocalConstant reports whether the variable name is a constant defined in
+// the environment itself, not in an enclosing one
+func (e *Environment) LocalConstant(name string) bool {
+	return e.constants[name]
+}
+
+// Assign updates the variable name in the environment that defines it, it
+// returns false when the variable isn't defined
+func (e *Environment) Assign(name string, val Object) bool {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			env.store[name] = val
+			return true
+		}
+	}
+	return false
+}
+
 func (e *Environment) String() string {
 	var out bytes.Buffer
 	for k, v := range e.store {
@@ -126,7 +376,7 @@ func (e *Environment) String() string {
 }
 
 type Function struct {
-	Parameters []*ast.Identifier
+	Parameters []*ast.Declaration
 	Body       *ast.BlockStatement
 	Env        *Environment
 }
@@ -140,7 +390,7 @@ func (f *Function) Inspect() string {
 
 	params := []string{}
 	for _, p := range f.Parameters {
-		params = append(params, p.String())
+		params = append(params, p.Pattern.String())
 	}
 
 	out.WriteString("fn")
@@ -152,3 +402,126 @@ func (f *Function) Inspect() string {
 
 	return out.String()
 }
+
+// BuiltinFunction is the implementation of a builtin
+type BuiltinFunction func(args ...Object) Object
+
+type Builtin struct {
+	Fn BuiltinFunction
+}
+
+func (b *Builtin) Type() Type {
+	return BuiltinObj
+}
+
+func (b *Builtin) Inspect() string {
+	return "builtin function"
+}
+
+// BuiltinNames are the names of the functions defined in every program. The
+// interpreter implements them, the other passes only need to know they exist.
+var BuiltinNames = []string{
+	"len", "puts", "first", "last", "rest", "push", "range", "int", "float",
+	"error", "ok", "err", "is_ok", "is_err", "unwrap", "unwrap_or",
+}
+
+// IsBuiltin tells whether name is the name of a builtin function
+func IsBuiltin(name string) bool {
+	for _, n := range BuiltinNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+type String struct {
+	Value string
+}
+
+func (s *String) Type() Type {
+	return StringObj
+}
+
+func (s *String) Inspect() string {
+	return s.Value
+}
+
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s.Value))
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+type Array struct {
+	Elements []Object
+}
+
+func (a *Array) Type() Type {
+	return ArrayObj
+}
+
+func (a *Array) Inspect() string {
+	elements := []string{}
+	for _, e := range a.Elements {
+		elements = append(elements, e.Inspect())
+	}
+
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// HashKey identifies the value of a hash key, equal keys have the same
+// HashKey
+type HashKey struct {
+	Type  Type
+	Value uint64
+}
+
+// Hashable is implemented by the objects that can be used as hash keys
+type Hashable interface {
+	HashKey() HashKey
+}
+
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+// Hash maps keys to values, it remembers the order the keys were added in
+type Hash struct {
+	Pairs map[HashKey]HashPair
+	keys  []HashKey
+}
+
+func NewHash() *Hash {
+	return &Hash{Pairs: map[HashKey]HashPair{}}
+}
+
+// Get returns the value stored under key
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	pair, ok := h.Pairs[key.HashKey()]
+	return pair.Value, ok
+}
+
+// Set stores value under key, a new key goes after the existing ones
+func (h *Hash) Set(key Object, value Object) {
+	hashKey := key.(Hashable).HashKey()
+	if _, ok := h.Pairs[hashKey]; !ok {
+		h.keys = append(h.keys, hashKey)
+	}
+	h.Pairs[hashKey] = HashPair{Key: key, Value: value}
+}
+
+func (h *Hash) Type() Type {
+	return HashObj
+}
+
+func (h *Hash) Inspect() string {
+	pairs := []string{}
+	for _, k := range h.keys {
+		pair := h.Pairs[k]
+		pairs = append(pairs, pair.Key.Inspect()+": "+pair.Value.Inspect())
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
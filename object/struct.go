@@ -0,0 +1,54 @@
+package object
+
+import "strings"
+
+// StructType is a record type declared with a struct statement, its
+// methods are added by impl statements
+type StructType struct {
+	Name    string
+	Fields  []string
+	Methods map[string]*Function
+}
+
+// Method returns the method called name, nil if the type has none
+func (st *StructType) Method(name string) *Function {
+	return st.Methods[name]
+}
+
+// HasField tells if name is one of the fields of the type
+func (st *StructType) HasField(name string) bool {
+	for _, f := range st.Fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (st *StructType) Type() Type {
+	return StructTypeObj
+}
+
+func (st *StructType) Inspect() string {
+	return "struct " + st.Name + " { " + strings.Join(st.Fields, ", ") + " }"
+}
+
+// Struct is a value of a struct type, it holds a value for every field of
+// the type
+type Struct struct {
+	StructType *StructType
+	Fields     map[string]Object
+}
+
+func (s *Struct) Type() Type {
+	return StructObj
+}
+
+func (s *Struct) Inspect() string {
+	fields := []string{}
+	for _, f := range s.StructType.Fields {
+		fields = append(fields, f+": "+s.Fields[f].Inspect())
+	}
+
+	return s.StructType.Name + " { " + strings.Join(fields, ", ") + " }"
+}
@@ -0,0 +1,139 @@
+package optimize
+
+import (
+	"github.com/rumpl/monkey-lang/ast"
+)
+
+// DeadBranchElimination replaces if expressions with a constant condition by
+// the branch that is taken
+func DeadBranchElimination(program *ast.Program) *ast.Program {
+	ast.Modify(program, func(node ast.Node) ast.Node {
+		switch node := node.(type) {
+		case *ast.Program:
+			node.Statements = eliminateBranches(node.Statements)
+		case *ast.BlockStatement:
+			node.Statements = eliminateBranches(node.Statements)
+		case *ast.IfExpression:
+			// An if used as a value can only be replaced when the branch
+			// taken is a single expression
+			branch, ok := takenBranch(node)
+			if !ok || branch == nil || len(branch.Statements) != 1 {
+				return node
+			}
+			if stmt, ok := branch.Statements[0].(*ast.ExpressionStatement); ok {
+				return stmt.Expression
+			}
+		}
+		return node
+	})
+
+	return program
+}
+
+// eliminateBranches splices the statements of the branch taken by if
+// statements with a constant condition into stmts. The value of a statement
+// list is the value of its last statement, so a last if that would produce
+// null is kept. A branch declaring variables is its own scope, its if is
+// kept so they don't leak into stmts.
+func eliminateBranches(stmts []ast.Statement) []ast.Statement {
+	result := []ast.Statement{}
+
+	for i, stmt := range stmts {
+		last := i == len(stmts)-1
+
+		es, ok := stmt.(*ast.ExpressionStatement)
+		if !ok {
+			result = append(result, stmt)
+			continue
+		}
+
+		ie, ok := es.Expression.(*ast.IfExpression)
+		if !ok {
+			result = append(result, stmt)
+			continue
+		}
+
+		branch, ok := takenBranch(ie)
+		switch {
+		case !ok:
+			result = append(result, stmt)
+		case branch == nil || len(branch.Statements) == 0:
+			if last {
+				result = append(result, stmt)
+			}
+		case declares(branch):
+			result = append(result, stmt)
+		default:
+			result = append(result, branch.Statements...)
+		}
+	}
+
+	return result
+}
+
+// declares tells if a block declares variables of its own
+func declares(block *ast.BlockStatement) bool {
+	for _, stmt := range block.Statements {
+		switch stmt.(type) {
+		case *ast.LetStatement, *ast.FunctionStatement, *ast.StructStatement:
+			return true
+		}
+	}
+
+	return false
+}
+
+// takenBranch returns the branch an if with a constant condition takes, the
+// branch is nil when the condition is false and there is no else.
+func takenBranch(ie *ast.IfExpression) (*ast.BlockStatement, bool) {
+	var truthy bool
+
+	switch cond := ie.Condition.(type) {
+	case *ast.Boolean:
+		truthy = cond.Value
+	case *ast.IntegerLiteral:
+		truthy = true
+	default:
+		return nil, false
+	}
+
+	if truthy {
+		return ie.Consequence, true
+	}
+
+	return ie.Alternative, true
+}
+
+// UnreachableCode removes the statements following a return, a break or a
+// continue. The fn statements stay, they are hoisted and can be called
+// before them.
+func UnreachableCode(program *ast.Program) *ast.Program {
+	ast.Modify(program, func(node ast.Node) ast.Node {
+		switch node := node.(type) {
+		case *ast.Program:
+			node.Statements = removeUnreachable(node.Statements)
+		case *ast.BlockStatement:
+			node.Statements = removeUnreachable(node.Statements)
+		}
+		return node
+	})
+
+	return program
+}
+
+func removeUnreachable(stmts []ast.Statement) []ast.Statement {
+	for i, stmt := range stmts {
+		switch stmt.(type) {
+		case *ast.ReturnStatement, *ast.BreakStatement, *ast.ContinueStatement, *ast.ThrowStatement:
+			result := stmts[:i+1]
+			for _, rest := range stmts[i+1:] {
+				if fs, ok := rest.(*ast.FunctionStatement); ok {
+					result = append(result, fs)
+				}
+			}
+			return result
+		}
+	}
+
+	return stmts
+}
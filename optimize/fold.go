@@ -0,0 +1,123 @@
+package optimize
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/rumpl/monkey-lang/ast"
+	"github.com/rumpl/monkey-lang/object"
+	"github.com/rumpl/monkey-lang/token"
+)
+
+// ConstantFolding replaces prefix and infix expressions on integer and
+// boolean literals with their value. Expressions that would fail at runtime
+// or overflow are left alone so the error or the big integer still happens.
+func ConstantFolding(program *ast.Program) *ast.Program {
+	ast.Modify(program, func(node ast.Node) ast.Node {
+		switch node := node.(type) {
+		case *ast.PrefixExpression:
+			if folded := foldPrefix(node); folded != nil {
+				return folded
+			}
+		case *ast.InfixExpression:
+			if folded := foldInfix(node); folded != nil {
+				return folded
+			}
+		}
+		return node
+	})
+
+	return program
+}
+
+func foldPrefix(node *ast.PrefixExpression) ast.Expression {
+	switch right := node.Right.(type) {
+	case *ast.IntegerLiteral:
+		switch node.Operator {
+		case "-":
+			if !object.Overflows("-", 0, right.Value) {
+				return integer(node.Token, -right.Value)
+			}
+		case "!":
+			return boolean(node.Token, false)
+		}
+	case *ast.Boolean:
+		if node.Operator == "!" {
+			return boolean(node.Token, !right.Value)
+		}
+	}
+
+	return nil
+}
+
+func foldInfix(node *ast.InfixExpression) ast.Expression {
+	switch left := node.Left.(type) {
+	case *ast.IntegerLiteral:
+		right, ok := node.Right.(*ast.IntegerLiteral)
+		if !ok {
+			return nil
+		}
+
+		l, r := left.Value, right.Value
+		switch node.Operator {
+		case "+", "-", "*":
+			if object.Overflows(node.Operator, l, r) {
+				return nil
+			}
+		}
+
+		switch node.Operator {
+		case "+":
+			return integer(node.Token, l+r)
+		case "-":
+			return integer(node.Token, l-r)
+		case "*":
+			return integer(node.Token, l*r)
+		case "/":
+			if r != 0 && !(l == math.MinInt64 && r == -1) {
+				return integer(node.Token, l/r)
+			}
+		case "<":
+			return boolean(node.Token, l < r)
+		case ">":
+			return boolean(node.Token, l > r)
+		case "==":
+			return boolean(node.Token, l == r)
+		case "!=":
+			return boolean(node.Token, l != r)
+		}
+	case *ast.Boolean:
+		right, ok := node.Right.(*ast.Boolean)
+		if !ok {
+			return nil
+		}
+
+		switch node.Operator {
+		case "==":
+			return boolean(node.Token, left.Value == right.Value)
+		case "!=":
+			return boolean(node.Token, left.Value != right.Value)
+		}
+	}
+
+	return nil
+}
+
+// integer returns an integer literal at the position of tok
+func integer(tok token.Token, value int64) *ast.IntegerLiteral {
+	literal := strconv.FormatInt(value, 10)
+	return &ast.IntegerLiteral{
+		Token: token.Token{Type: token.INT, Literal: literal, Line: tok.Line, Column: tok.Column},
+		Value: value,
+	}
+}
+
+// boolean returns a boolean literal at the position of tok
+func boolean(tok token.Token, value bool) *ast.Boolean {
+	t := token.Token{Type: token.FALSE, Literal: "false", Line: tok.Line, Column: tok.Column}
+	if value {
+		t.Type = token.TRUE
+		t.Literal = "true"
+	}
+	return &ast.Boolean{Token: t, Value: value}
+}
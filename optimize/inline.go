@@ -0,0 +1,319 @@
+package optimize
+
+import (
+	"github.com/rumpl/monkey-lang/ast"
+)
+
+// maxInlineSize is the maximum number of nodes in the body of a function
+// that gets inlined
+const maxInlineSize = 16
+
+type inlineCandidate struct {
+	params []*ast.Identifier
+	body   ast.Expression
+}
+
+// Inline replaces calls to small non-recursive top-level functions with
+// their body. A function is only inlined when its body is a single
+// expression that refers to nothing but its parameters and top-level names
+// bound once in the whole program, so the inlined code can't see a different
+// binding.
+func Inline(program *ast.Program) *ast.Program {
+	bindings := countBindings(program)
+
+	globals := map[string]bool{}
+	for _, stmt := range program.Statements {
+		switch stmt := stmt.(type) {
+		case *ast.FunctionStatement:
+			globals[stmt.Name] = true
+		case *ast.LetStatement:
+			for _, name := range ast.PatternNames(stmt.Name.Pattern) {
+				globals[name.Value] = true
+			}
+		}
+	}
+
+	// unique tells whether a name always refers to the same top-level binding
+	unique := func(name string) bool {
+		return globals[name] && bindings[name] == 1
+	}
+
+	candidates := map[string]*inlineCandidate{}
+	for _, stmt := range program.Statements {
+		var name string
+		var params []*ast.Declaration
+		var body *ast.BlockStatement
+
+		switch stmt := stmt.(type) {
+		case *ast.FunctionStatement:
+			name, params, body = stmt.Name, stmt.Parameters, stmt.Body
+		case *ast.LetStatement:
+			fn, ok := stmt.Value.(*ast.FunctionLiteral)
+			if !ok || stmt.Name.Variable() == nil {
+				continue
+			}
+			name, params, body = stmt.Name.Variable().Value, fn.Parameters, fn.Body
+		default:
+			continue
+		}
+
+		if !unique(name) {
+			continue
+		}
+
+		exp := singleExpression(body)
+		if exp == nil {
+			continue
+		}
+
+		allowed := map[string]bool{}
+		ids := []*ast.Identifier{}
+		simple := true
+		for _, p := range params {
+			id := p.Variable()
+			simple = simple && id != nil && p.Default == nil && !p.Rest
+			if id != nil {
+				allowed[id.Value] = true
+				ids = append(ids, id)
+			}
+		}
+		// The arguments of a destructured, defaulted or rest parameter
+		// can't be substituted
+		if !simple {
+			continue
+		}
+
+		size := 0
+		if !inlinable(exp, name, allowed, unique, &size) {
+			continue
+		}
+
+		candidates[name] = &inlineCandidate{params: ids, body: exp}
+	}
+
+	if len(candidates) == 0 {
+		return program
+	}
+
+	ast.Modify(program, func(node ast.Node) ast.Node {
+		call, ok := node.(*ast.CallExpression)
+		if !ok {
+			return node
+		}
+
+		ident, ok := call.Function.(*ast.Identifier)
+		if !ok {
+			return node
+		}
+
+		candidate, ok := candidates[ident.Value]
+		if !ok || len(candidate.params) != len(call.Arguments) {
+			return node
+		}
+
+		args := map[string]ast.Expression{}
+		for i, p := range candidate.params {
+			uses := countUses(candidate.body, p.Value)
+			if !canSubstitute(call.Arguments[i], uses) {
+				return node
+			}
+			args[p.Value] = call.Arguments[i]
+		}
+
+		return substitute(candidate.body, args)
+	})
+
+	return program
+}
+
+// singleExpression returns the expression of a function body made of a
+// single expression or return statement
+func singleExpression(body *ast.BlockStatement) ast.Expression {
+	if len(body.Statements) != 1 {
+		return nil
+	}
+
+	switch stmt := body.Statements[0].(type) {
+	case *ast.ExpressionStatement:
+		return stmt.Expression
+	case *ast.ReturnStatement:
+		return stmt.ReturnValue
+	}
+
+	return nil
+}
+
+// inlinable reports whether exp only uses simple expressions and refers to
+// its parameters or to unique top-level names, other than self
+func inlinable(exp ast.Expression, self string, params map[string]bool, unique func(string) bool, size *int) bool {
+	*size++
+	if *size > maxInlineSize {
+		return false
+	}
+
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral, *ast.Boolean:
+		return true
+	case *ast.Identifier:
+		if exp.Value == self {
+			return false
+		}
+		return params[exp.Value] || unique(exp.Value)
+	case *ast.PrefixExpression:
+		return inlinable(exp.Right, self, params, unique, size)
+	case *ast.InfixExpression:
+		return inlinable(exp.Left, self, params, unique, size) &&
+			inlinable(exp.Right, self, params, unique, size)
+	case *ast.CallExpression:
+		if !inlinable(exp.Function, self, params, unique, size) {
+			return false
+		}
+		for _, arg := range exp.Arguments {
+			if !inlinable(arg, self, params, unique, size) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// canSubstitute reports whether arg can replace a parameter used uses times
+// without changing what the program does. Arguments are evaluated before the
+// call, so only literals can be dropped and only side effect free
+// expressions can be moved or copied.
+func canSubstitute(arg ast.Expression, uses int) bool {
+	switch arg.(type) {
+	case *ast.IntegerLiteral, *ast.Boolean:
+		return true
+	case *ast.Identifier:
+		return uses > 0
+	}
+
+	return uses == 1 && pure(arg)
+}
+
+func pure(exp ast.Expression) bool {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral, *ast.Boolean, *ast.Identifier:
+		return true
+	case *ast.PrefixExpression:
+		return pure(exp.Right)
+	case *ast.InfixExpression:
+		return pure(exp.Left) && pure(exp.Right)
+	}
+
+	return false
+}
+
+func countUses(exp ast.Expression, name string) int {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		if exp.Value == name {
+			return 1
+		}
+	case *ast.PrefixExpression:
+		return countUses(exp.Right, name)
+	case *ast.InfixExpression:
+		return countUses(exp.Left, name) + countUses(exp.Right, name)
+	case *ast.CallExpression:
+		n := countUses(exp.Function, name)
+		for _, arg := range exp.Arguments {
+			n += countUses(arg, name)
+		}
+		return n
+	}
+
+	return 0
+}
+
+// substitute returns a copy of exp where the parameters are replaced by the
+// arguments of the call
+func substitute(exp ast.Expression, args map[string]ast.Expression) ast.Expression {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		if arg, ok := args[exp.Value]; ok {
+			return arg
+		}
+		return &ast.Identifier{Token: exp.Token, Value: exp.Value}
+	case *ast.IntegerLiteral:
+		return &ast.IntegerLiteral{Token: exp.Token, Value: exp.Value}
+	case *ast.Boolean:
+		return &ast.Boolean{Token: exp.Token, Value: exp.Value}
+	case *ast.PrefixExpression:
+		return &ast.PrefixExpression{
+			Token:    exp.Token,
+			Operator: exp.Operator,
+			Right:    substitute(exp.Right, args),
+		}
+	case *ast.InfixExpression:
+		return &ast.InfixExpression{
+			Token:    exp.Token,
+			Operator: exp.Operator,
+			Left:     substitute(exp.Left, args),
+			Right:    substitute(exp.Right, args),
+		}
+	case *ast.CallExpression:
+		call := &ast.CallExpression{
+			Token:    exp.Token,
+			Function: substitute(exp.Function, args),
+		}
+		for _, arg := range exp.Arguments {
+			call.Arguments = append(call.Arguments, substitute(arg, args))
+		}
+		return call
+	}
+
+	return exp
+}
+
+// countBindings counts how many times each name is bound or assigned to in
+// the program
+func countBindings(program *ast.Program) map[string]int {
+	bindings := map[string]int{}
+
+	names := func(ids []*ast.Identifier) {
+		for _, id := range ids {
+			bindings[id.Value]++
+		}
+	}
+	params := func(decls []*ast.Declaration) {
+		for _, decl := range decls {
+			names(ast.PatternNames(decl.Pattern))
+		}
+	}
+
+	ast.Modify(program, func(node ast.Node) ast.Node {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			names(ast.PatternNames(node.Name.Pattern))
+		case *ast.FunctionStatement:
+			bindings[node.Name]++
+			params(node.Parameters)
+		case *ast.FunctionLiteral:
+			params(node.Parameters)
+		case *ast.ImplStatement:
+			for _, m := range node.Methods {
+				params(m.Parameters)
+			}
+		case *ast.AssignExpression:
+			if left, ok := node.Left.(*ast.Identifier); ok {
+				bindings[left.Value]++
+			}
+		case *ast.ForInExpression:
+			names(node.Names)
+		case *ast.MatchExpression:
+			for _, arm := range node.Arms {
+				names(ast.PatternNames(arm.Pattern))
+			}
+		case *ast.TryExpression:
+			if node.Param != nil {
+				names([]*ast.Identifier{node.Param})
+			}
+		}
+		return node
+	})
+
+	return bindings
+}
@@ -0,0 +1,97 @@
+package optimize
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rumpl/monkey-lang/ast"
+)
+
+// Pass is a named rewrite of a program
+type Pass struct {
+	Name string
+	Run  func(*ast.Program) *ast.Program
+}
+
+// Passes are all the available passes, in the order they are run. Inlining
+// comes first so that the inlined code gets folded.
+var Passes = []Pass{
+	{Name: "inline", Run: Inline},
+	{Name: "fold", Run: ConstantFolding},
+	{Name: "deadbranch", Run: DeadBranchElimination},
+	{Name: "unreachable", Run: UnreachableCode},
+}
+
+// Pipeline runs a set of passes over a program
+type Pipeline struct {
+	passes []Pass
+}
+
+// New returns a pipeline running every pass
+func New() *Pipeline {
+	return &Pipeline{passes: Passes}
+}
+
+// Parse returns a pipeline from a comma separated list of pass names. A name
+// prefixed with "-" removes the pass from the pipeline, "none" removes every
+// pass and an empty spec runs every pass. For example "-inline" runs every
+// pass but inlining.
+func Parse(spec string) (*Pipeline, error) {
+	p := &Pipeline{}
+
+	enabled := map[string]bool{}
+	for _, pass := range Passes {
+		enabled[pass.Name] = true
+	}
+
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		value := true
+		if strings.HasPrefix(name, "-") {
+			name = name[1:]
+			value = false
+		}
+
+		switch {
+		case name == "":
+		case name == "none":
+			for n := range enabled {
+				enabled[n] = false
+			}
+		case name == "all":
+			for n := range enabled {
+				enabled[n] = true
+			}
+		default:
+			if _, ok := enabled[name]; !ok {
+				return nil, fmt.Errorf("unknown optimization pass %q", name)
+			}
+			enabled[name] = value
+		}
+	}
+
+	for _, pass := range Passes {
+		if enabled[pass.Name] {
+			p.passes = append(p.passes, pass)
+		}
+	}
+
+	return p, nil
+}
+
+// Names returns the names of the passes the pipeline runs
+func (p *Pipeline) Names() []string {
+	names := []string{}
+	for _, pass := range p.passes {
+		names = append(names, pass.Name)
+	}
+	return names
+}
+
+// Run runs every pass of the pipeline over the program
+func (p *Pipeline) Run(program *ast.Program) *ast.Program {
+	for _, pass := range p.passes {
+		program = pass.Run(program)
+	}
+	return program
+}
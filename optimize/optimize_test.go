@@ -0,0 +1,197 @@
+package optimize
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rumpl/monkey-lang/ast"
+	"github.com/rumpl/monkey-lang/eval"
+	"github.com/rumpl/monkey-lang/lexer"
+	"github.com/rumpl/monkey-lang/object"
+	"github.com/rumpl/monkey-lang/parser"
+)
+
+func TestConstantFolding(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"12 * 12", "144"},
+		{"1 + 2 * 3 - 4", "3"},
+		{"-(2 * 3)", "-6"},
+		{"7 / 2", "3"},
+		{"1 / 0", "(1 / 0)"},
+		{"1 < 2", "true"},
+		{"2 == 2", "true"},
+		{"true != false", "true"},
+		{"!true", "false"},
+		{"!5", "false"},
+		{"true + false", "(true + false)"},
+		{"a + 1 * 2", "(a + 2)"},
+		{"4294967296 * 4294967296", "(4294967296 * 4294967296)"},
+		{"9223372036854775807 + 1", "(9223372036854775807 + 1)"},
+		{"-9223372036854775807 - 2", "(-9223372036854775807 - 2)"},
+		{"9223372036854775807 - 1", "9223372036854775806"},
+		{"(-9223372036854775807 - 1) / -1", "(-9223372036854775808 / -1)"},
+		{"(-9223372036854775807 - 1) / 1", "-9223372036854775808"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			testPass(t, ConstantFolding, tt.input, tt.expected)
+		})
+	}
+}
+
+func TestDeadBranchElimination(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = if (true) { 1 } else { 2 };", "let a = 1;"},
+		{"let a = if (false) { 1 } else { 2 };", "let a = 2;"},
+		{"let a = if (false) { 1 };", "let a = iffalse 1;"},
+		{"if (1) { a; 1 }; 2", "a12"},
+		{"if (1) { let a = 1; a }; 2", "if1 let a = 1;a2"},
+		{"if (false) { 1 }; 2", "2"},
+		{"1; if (false) { 1 }", "1iffalse 1"},
+		{"if (a) { 1 }", "ifa 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			testPass(t, DeadBranchElimination, tt.input, tt.expected)
+		})
+	}
+}
+
+func TestUnreachableCode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"return 1; 2; 3;", "return 1;"},
+		{"let f = fn() { 1; return 2; 3 };", "let f = fn() 1return 2;;"},
+		{"if (a) { return 1; 2 } 3", "ifa return 1;3"},
+		{"let f = fn() { throw 1; 2 };", "let f = fn() throw 1;;"},
+		{"let g = fn() { return f(); 1; fn f() { 42 } };", "let g = fn() return f();fn() 42;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			testPass(t, UnreachableCode, tt.input, tt.expected)
+		})
+	}
+}
+
+func TestInline(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let sq = fn(x) { x * x }; sq(3)", "let sq = fn(x) (x * x);(3 * 3)"},
+		{"let sq = fn(x) { x * x }; sq(a + 1)", "let sq = fn(x) (x * x);sq((a + 1))"},
+		{"let inc = fn(x) { x + 1 }; inc(a + 1)", "let inc = fn(x) (x + 1);((a + 1) + 1)"},
+		{"let k = fn(x) { 1 }; k(f())", "let k = fn(x) 1;k(f())"},
+		{"let k = fn(x) { 1 }; k(2)", "let k = fn(x) 1;1"},
+		{"let f = fn(x) { f(x) }; f(1)", "let f = fn(x) f(x);f(1)"},
+		{"let f = fn(x) { x }; f(1, 2)", "let f = fn(x) x;f(1, 2)"},
+		{"let f = fn(x) { x }; let f = 2; f(1)", "let f = fn(x) x;let f = 2;f(1)"},
+		{"let y = 1; let f = fn(x) { x + y }; let g = fn(y) { f(y) }; g(2)",
+			"let y = 1;let f = fn(x) (x + y);let g = fn(y) f(y);f(2)"},
+		{"let f = fn(x) { x + y }; let g = fn(a) { let y = 2; f(a) }; g(2)",
+			"let f = fn(x) (x + y);let g = fn(a) let y = 2;f(a);g(2)"},
+		{"fn test() { return 12 * 12; } fn main() { return test(); }",
+			"fn() return (12 * 12);fn() return (12 * 12);"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			testPass(t, Inline, tt.input, tt.expected)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected []string
+	}{
+		{"", []string{"inline", "fold", "deadbranch", "unreachable"}},
+		{"all", []string{"inline", "fold", "deadbranch", "unreachable"}},
+		{"none", []string{}},
+		{"-inline", []string{"fold", "deadbranch", "unreachable"}},
+		{"none,fold", []string{"fold"}},
+	}
+
+	for _, tt := range tests {
+		p, err := Parse(tt.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(p.Names(), tt.expected) {
+			t.Errorf("Parse(%q) expected %v, got %v", tt.spec, tt.expected, p.Names())
+		}
+	}
+
+	if _, err := Parse("unknown"); err == nil {
+		t.Errorf("expected an error for an unknown pass")
+	}
+}
+
+// TestSemantics checks that the optimized programs evaluate to the same
+// result as the original ones
+func TestSemantics(t *testing.T) {
+	tests := []string{
+		"5 + 5 * 2 - -3",
+		"let sq = fn(x) { x * x }; sq(sq(3))",
+		"let add = fn(a, b) { a + b }; let twice = fn(x) { add(x, x) }; twice(21)",
+		"if (1 < 2) { 10 } else { 20 }",
+		"let f = fn() { if (true) { return 1; } 2 }; f()",
+		"let a = 1; if (false) { a = 2; }; a",
+		"let a = 1; if (true) { let a = 2; }; a",
+		"let a = 1; if (false) { 1 } else { const a = 2; }; a",
+		"if (true) { fn f() { 1 } }; f()",
+		"for (let i = 0; i < 10; i = i + 1) { if (true) { i * 2 } }",
+		"if (false) { 1 }",
+		"5 + true",
+		"4294967296 * 4294967296",
+		"9223372036854775807 + 1",
+		"-(-9223372036854775807 - 1)",
+		"(-9223372036854775807 - 1) / -1",
+		"fn main() { return f(); fn f() { 42 } }",
+		"let self = 1; let f = fn() { self }; struct P { x } impl P { fn m(self) { f() } } P { x: 5 }.m()",
+	}
+
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			expected := testEval(parse(t, input))
+			actual := testEval(New().Run(parse(t, input)))
+
+			if expected.Inspect() != actual.Inspect() {
+				t.Errorf("wrong result, expected %s, got %s", expected.Inspect(), actual.Inspect())
+			}
+		})
+	}
+}
+
+func testPass(t *testing.T, pass func(*ast.Program) *ast.Program, input string, expected string) {
+	program := pass(parse(t, input))
+	if program.String() != expected {
+		t.Errorf("expected %q, got %q", expected, program.String())
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
+
+func testEval(program *ast.Program) object.Object {
+	return eval.Run(program, object.NewEnvironment())
+}
@@ -1,8 +1,11 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
 	"strconv"
+	"strings"
 
 	"github.com/rumpl/monkey-lang/ast"
 	"github.com/rumpl/monkey-lang/lexer"
@@ -13,26 +16,55 @@ const (
 	_ int = iota
 	LOWEST
 	ASSIGN
+	OR          // ||
+	AND         // &&
+	BITOR       // |
+	BITXOR      // ^
+	BITAND      // &
 	EQUALS      // ==
 	LESSGREATER // < or >
+	SHIFT       // << or >>
 	SUM         // +
 	PRODUCT     // *
 	PREFIX      // -X or !X
 	CALL        // func(X)
-
+	INDEX       // array[index]
 )
 
 var precedences = map[token.Type]int{
-	token.EQ:       EQUALS,
-	token.NEQ:      EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
-	token.ASSIGN:   ASSIGN,
+	token.EQ:        EQUALS,
+	token.NEQ:       EQUALS,
+	token.OR:        OR,
+	token.AND:       AND,
+	token.PIPE:      BITOR,
+	token.CARET:     BITXOR,
+	token.AMPERSAND: BITAND,
+	token.SHL:       SHIFT,
+	token.SHR:       SHIFT,
+	token.USHR:      SHIFT,
+	token.LT:        LESSGREATER,
+	token.GT:        LESSGREATER,
+	token.LTE:       LESSGREATER,
+	token.GTE:       LESSGREATER,
+	token.PLUS:      SUM,
+	token.MINUS:     SUM,
+	token.SLASH:     PRODUCT,
+	token.ASTERISK:  PRODUCT,
+	token.PERCENT:   PRODUCT,
+	token.LPAREN:    CALL,
+	token.LBRACE:    CALL,
+	token.QUESTION:  CALL,
+	token.LBRACKET:  INDEX,
+	token.DOT:       INDEX,
+	token.ASSIGN:    ASSIGN,
+
+	token.PLUS_ASSIGN:     ASSIGN,
+	token.MINUS_ASSIGN:    ASSIGN,
+	token.ASTERISK_ASSIGN: ASSIGN,
+	token.SLASH_ASSIGN:    ASSIGN,
+	token.PERCENT_ASSIGN:  ASSIGN,
+	token.INCREMENT:       CALL,
+	token.DECREMENT:       CALL,
 }
 
 type (
@@ -65,8 +97,14 @@ func New(l *lexer.Lexer) *Parser {
 	p.prefixParseFns = make(map[token.Type]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
+	p.registerPrefix(token.ILLEGAL, p.parseIllegal)
+	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.TILDE, p.parsePrefixExpression)
 
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
@@ -75,10 +113,15 @@ func New(l *lexer.Lexer) *Parser {
 
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FOR, p.parseForExpression)
+	p.registerPrefix(token.WHILE, p.parseWhileExpression)
+	p.registerPrefix(token.TRY, p.parseTryExpression)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
 
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 
 	p.infixParseFns = make(map[token.Type]infixParseFn)
+	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.DOT, p.parseFieldExpression)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
@@ -87,9 +130,29 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.NEQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LTE, p.parseInfixExpression)
+	p.registerInfix(token.GTE, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
+	p.registerInfix(token.AMPERSAND, p.parseInfixExpression)
+	p.registerInfix(token.PIPE, p.parseInfixExpression)
+	p.registerInfix(token.CARET, p.parseInfixExpression)
+	p.registerInfix(token.SHL, p.parseInfixExpression)
+	p.registerInfix(token.SHR, p.parseInfixExpression)
+	p.registerInfix(token.USHR, p.parseInfixExpression)
 
 	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.SLASH_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PERCENT_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.INCREMENT, p.parseIncrementExpression)
+	p.registerInfix(token.DECREMENT, p.parseIncrementExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.LBRACE, p.parseStructLiteral)
+	p.registerInfix(token.QUESTION, p.parsePostfixExpression)
 
 	return p
 }
@@ -125,26 +188,89 @@ func (p *Parser) ParseProgram() *ast.Program {
 
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
-	case token.LET:
+	case token.LET, token.CONST:
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
+	case token.STRUCT:
+		return p.parseStructStatement()
+	case token.IMPL:
+		return p.parseImplStatement()
 	case token.FUNCTION:
-		return p.parseFunctionStatement()
+		// fn(x) { ... } at the start of a statement is a function literal
+		if p.peekTokenIs(token.IDENT) {
+			return p.parseFunctionStatement()
+		}
+		return p.parseExpressionStatement()
+	case token.BREAK:
+		stmt := &ast.BreakStatement{Token: p.curToken}
+		stmt.Label = p.parseJumpLabel()
+		return stmt
+	case token.CONTINUE:
+		stmt := &ast.ContinueStatement{Token: p.curToken}
+		stmt.Label = p.parseJumpLabel()
+		return stmt
+	case token.IDENT:
+		if p.peekTokenIs(token.COLON) {
+			return p.parseLabeledLoop()
+		}
+		return p.parseExpressionStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
 }
 
+// parseJumpLabel parses the optional label following break and continue
+func (p *Parser) parseJumpLabel() *ast.Identifier {
+	var label *ast.Identifier
+	// A name on the next line is the statement following the jump
+	if p.peekTokenIs(token.IDENT) && p.peekToken.Line == p.curToken.Line {
+		p.nextToken()
+		label = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return label
+}
+
+// parseLabeledLoop parses "label: for (...) {}" and "label: while (...) {}"
+func (p *Parser) parseLabeledLoop() ast.Statement {
+	label := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	p.nextToken()
+	p.nextToken()
+
+	stmt := p.parseExpressionStatement()
+	switch loop := stmt.Expression.(type) {
+	case *ast.ForExpression:
+		loop.Label = label
+	case *ast.ForInExpression:
+		loop.Label = label
+	case *ast.WhileExpression:
+		loop.Label = label
+	default:
+		msg := fmt.Sprintf("label %s must be followed by a loop", label.Value)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	return stmt
+}
+
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.curToken}
 
-	if !p.expectPeek(token.IDENT) {
+	p.nextToken()
+	stmt.Name = p.parseDeclaration()
+	if stmt.Name == nil {
 		return nil
 	}
 
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
@@ -174,6 +300,20 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
 
@@ -197,6 +337,7 @@ func (p *Parser) parseFunctionStatement() *ast.FunctionStatement {
 	}
 
 	stmt.Parameters = p.parseFunctionParameters()
+	stmt.ReturnType = p.parseReturnType()
 
 	if !p.expectPeek(token.LBRACE) {
 		return nil
@@ -207,6 +348,90 @@ func (p *Parser) parseFunctionStatement() *ast.FunctionStatement {
 	return stmt
 }
 
+// parseStructStatement parses "struct Name { field, ... }"
+func (p *Parser) parseStructStatement() *ast.StructStatement {
+	stmt := &ast.StructStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		field := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if seen[field.Value] {
+			p.errors = append(p.errors, fmt.Sprintf("duplicate field %s in struct %s", field.Value, stmt.Name.Value))
+		}
+		seen[field.Value] = true
+		stmt.Fields = append(stmt.Fields, field)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseImplStatement parses "impl Name { fn method(self, ...) { ... } ... }"
+func (p *Parser) parseImplStatement() *ast.ImplStatement {
+	stmt := &ast.ImplStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.FUNCTION) {
+			return nil
+		}
+		if !p.peekTokenIs(token.IDENT) {
+			p.peekError(token.IDENT)
+			return nil
+		}
+		method := p.parseFunctionStatement()
+		if method == nil {
+			return nil
+		}
+		if len(method.Parameters) == 0 || method.Parameters[0].Variable() == nil || method.Parameters[0].Default != nil || method.Parameters[0].Rest {
+			p.errors = append(p.errors, fmt.Sprintf("method %s must take its receiver as first parameter", method.Name))
+		}
+		if seen[method.Name] {
+			p.errors = append(p.errors, fmt.Sprintf("duplicate method %s in impl %s", method.Name, stmt.Name.Value))
+		}
+		seen[method.Name] = true
+		stmt.Methods = append(stmt.Methods, method)
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return stmt
+}
+
 func (p *Parser) noPrefixParseFnError(t token.Type) {
 	msg := fmt.Sprintf("no prefix parse function for %s found", t)
 	p.errors = append(p.errors, msg)
@@ -258,7 +483,20 @@ func (p *Parser) parseIdentifier() ast.Expression {
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	integer := &ast.IntegerLiteral{Token: p.curToken}
 
-	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	// Base 0 reads the base from the prefix, a decimal integer is parsed in
+	// base 10 so that a leading zero doesn't make it octal
+	literal, base := p.curToken.Literal, 0
+	if !strings.ContainsAny(literal, "xXoObB") {
+		literal, base = strings.ReplaceAll(literal, "_", ""), 10
+	}
+
+	value, err := strconv.ParseInt(literal, base, 64)
+	// An integer too large for an int64 is kept whole, it is a BigInt
+	if errors.Is(err, strconv.ErrRange) {
+		if n, ok := new(big.Int).SetString(literal, base); ok {
+			return &ast.BigIntegerLiteral{Token: p.curToken, Value: n}
+		}
+	}
 	if err != nil {
 		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
 
@@ -270,6 +508,38 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	return integer
 }
 
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	float := &ast.FloatLiteral{Token: p.curToken}
+
+	value, err := strconv.ParseFloat(strings.ReplaceAll(p.curToken.Literal, "_", ""), 64)
+	if err != nil {
+		msg := fmt.Sprintf("could not parse %q as float", p.curToken.Literal)
+
+		p.errors = append(p.errors, msg)
+	}
+
+	float.Value = value
+
+	return float
+}
+
+// parseIllegal reports a token the lexer didn't recognize
+func (p *Parser) parseIllegal() ast.Expression {
+	literal := p.curToken.Literal
+
+	msg := fmt.Sprintf("illegal token %q at %s", literal, p.curToken.Pos())
+	if reason := lexer.NumberError(literal); literal != "" && isDigitOrDot(literal[0]) && reason != "" {
+		msg = fmt.Sprintf("malformed number %q at %s: %s", literal, p.curToken.Pos(), reason)
+	}
+	p.errors = append(p.errors, msg)
+
+	return nil
+}
+
+func isDigitOrDot(ch byte) bool {
+	return ch == '.' || ('0' <= ch && ch <= '9')
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{
 		Token: p.curToken,
@@ -354,6 +624,10 @@ func (p *Parser) parseForExpression() ast.Expression {
 	}
 
 	p.nextToken()
+	if p.curTokenIs(token.IDENT) && (p.peekTokenIs(token.IN) || p.peekTokenIs(token.COMMA)) {
+		return p.parseForInExpression(expression.Token)
+	}
+
 	expression.Initial = p.parseLetStatement()
 
 	p.nextToken()
@@ -376,6 +650,301 @@ func (p *Parser) parseForExpression() ast.Expression {
 	return expression
 }
 
+// parseForInExpression parses the rest of "for (x in iterable) {}" and
+// "for (k, v in iterable) {}" from the first name
+func (p *Parser) parseForInExpression(tok token.Token) ast.Expression {
+	expression := &ast.ForInExpression{Token: tok}
+
+	expression.Names = append(expression.Names, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+	if p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		expression.Names = append(expression.Names, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+	}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Body = p.parseBlockStatement()
+
+	return expression
+}
+
+func (p *Parser) parseWhileExpression() ast.Expression {
+	expression := &ast.WhileExpression{
+		Token: p.curToken,
+	}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Body = p.parseBlockStatement()
+
+	return expression
+}
+
+// parseTryExpression parses "try {} catch (e) {} finally {}", the name of the
+// error and one of the two clauses are optional
+func (p *Parser) parseTryExpression() ast.Expression {
+	expression := &ast.TryExpression{
+		Token: p.curToken,
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Body = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			expression.Param = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			if !p.expectPeek(token.RPAREN) {
+				return nil
+			}
+		}
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		expression.Catch = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		expression.Finally = p.parseBlockStatement()
+	}
+
+	if expression.Catch == nil && expression.Finally == nil {
+		msg := fmt.Sprintf("try at %s must be followed by catch or finally", expression.Token.Pos())
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	return expression
+}
+
+// parseMatchExpression parses "match (subject) { pattern => body, ... }", the
+// comma after an arm whose body is a block can be left out
+func (p *Parser) parseMatchExpression() ast.Expression {
+	expression := &ast.MatchExpression{
+		Token: p.curToken,
+	}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		expression.Arms = append(expression.Arms, arm)
+
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+		} else if !p.curTokenIs(token.RBRACE) && !p.peekTokenIs(token.RBRACE) {
+			p.peekError(token.COMMA)
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return expression
+}
+
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{Pattern: p.parsePattern()}
+	if arm.Pattern == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.IF) {
+		p.nextToken()
+		p.nextToken()
+		arm.Guard = p.parseExpression(LOWEST)
+	}
+
+	if !p.expectPeek(token.FATARROW) {
+		return nil
+	}
+
+	p.nextToken()
+	if p.curTokenIs(token.LBRACE) {
+		arm.Body = p.parseBlockStatement()
+		return arm
+	}
+
+	// A single expression is the only statement of the body
+	stmt := &ast.ExpressionStatement{Token: p.curToken, Expression: p.parseExpression(LOWEST)}
+	arm.Body = &ast.BlockStatement{Token: stmt.Token, Statements: []ast.Statement{stmt}}
+
+	return arm
+}
+
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.curToken.Type {
+	case token.IDENT:
+		if p.curToken.Literal == "_" {
+			return &ast.WildcardPattern{Token: p.curToken}
+		}
+		return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	case token.INT, token.FLOAT, token.STRING, token.TRUE, token.FALSE:
+		return p.prefixParseFns[p.curToken.Type]()
+	case token.MINUS:
+		if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.FLOAT) {
+			break
+		}
+		return p.parsePrefixExpression()
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	case token.LBRACE:
+		return p.parseHashPattern()
+	}
+
+	msg := fmt.Sprintf("expected a pattern but got %s instead", p.curToken.Type)
+	p.errors = append(p.errors, msg)
+	return nil
+}
+
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	pattern := &ast.ArrayPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+
+		if p.curTokenIs(token.ELLIPSIS) {
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			pattern.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			// The rest is always last
+			break
+		}
+
+		el := p.parsePattern()
+		if el == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, el)
+
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return pattern
+}
+
+func (p *Parser) parseHashPattern() ast.Pattern {
+	pattern := &ast.HashPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		var key ast.Expression
+		switch p.curToken.Type {
+		case token.INT, token.STRING, token.TRUE, token.FALSE:
+			key = p.prefixParseFns[p.curToken.Type]()
+		case token.IDENT:
+			key = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+			if p.peekTokenIs(token.COMMA) || p.peekTokenIs(token.RBRACE) {
+				// {name} binds the value of the key "name" to name
+				value := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+				pattern.Pairs = append(pattern.Pairs, ast.HashPatternPair{Key: key, Value: value})
+				if p.peekTokenIs(token.COMMA) {
+					p.nextToken()
+				}
+				continue
+			}
+		default:
+			msg := fmt.Sprintf("expected a hash key but got %s instead", p.curToken.Type)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		value := p.parsePattern()
+		if value == nil {
+			return nil
+		}
+
+		pattern.Pairs = append(pattern.Pairs, ast.HashPatternPair{Key: key, Value: value})
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return pattern
+}
+
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	lit := &ast.FunctionLiteral{
 		Token: p.curToken,
@@ -386,6 +955,7 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	}
 
 	lit.Parameters = p.parseFunctionParameters()
+	lit.ReturnType = p.parseReturnType()
 
 	if !p.expectPeek(token.LBRACE) {
 		return nil
@@ -396,26 +966,39 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	return lit
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	params := []*ast.Identifier{}
+func (p *Parser) parseFunctionParameters() []*ast.Declaration {
+	params := []*ast.Declaration{}
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
 		return params
 	}
 
-	p.nextToken()
-
-	param := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	params = append(params, param)
-
-	for p.peekTokenIs(token.COMMA) {
+	for {
 		p.nextToken()
-		p.nextToken()
-		param := &ast.Identifier{
-			Token: p.curToken,
-			Value: p.curToken.Literal,
+
+		param := p.parseParameter()
+		if param == nil {
+			return nil
+		}
+		if len(params) > 0 {
+			last := params[len(params)-1]
+			if last.Rest {
+				msg := fmt.Sprintf("rest parameter %s must be the last parameter", last.Pattern)
+				p.errors = append(p.errors, msg)
+				return nil
+			}
+			if last.Default != nil && param.Default == nil && !param.Rest {
+				msg := fmt.Sprintf("parameter %s without a default follows a parameter with one", param)
+				p.errors = append(p.errors, msg)
+				return nil
+			}
 		}
 		params = append(params, param)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
 	}
 
 	if !p.expectPeek(token.RPAREN) {
@@ -425,6 +1008,122 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	return params
 }
 
+// parseParameter parses a parameter declaration, either ...name collecting
+// the arguments left or a declaration with an optional "= default" value
+func (p *Parser) parseParameter() *ast.Declaration {
+	if p.curTokenIs(token.ELLIPSIS) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		param := p.parseDeclaration()
+		param.Rest = true
+		return param
+	}
+
+	param := p.parseDeclaration()
+	if param == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.ASSIGN) {
+		p.nextToken()
+		p.nextToken()
+		param.Default = p.parseExpression(LOWEST)
+		if param.Default == nil {
+			return nil
+		}
+	}
+
+	return param
+}
+
+// parseDeclaration parses the name of a variable with its optional type, or
+// the array or hash pattern destructuring its value
+func (p *Parser) parseDeclaration() *ast.Declaration {
+	switch p.curToken.Type {
+	case token.IDENT:
+		id := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		return &ast.Declaration{Pattern: id, Type: p.parseTypeAnnotation()}
+	case token.LBRACKET, token.LBRACE:
+		pattern := p.parsePattern()
+		if pattern == nil {
+			return nil
+		}
+		return &ast.Declaration{Pattern: pattern}
+	}
+
+	msg := fmt.Sprintf("expected a name or a pattern but got %s instead", p.curToken.Type)
+	p.errors = append(p.errors, msg)
+	return nil
+}
+
+// parseTypeAnnotation parses the optional ": type" following the name of a
+// variable
+func (p *Parser) parseTypeAnnotation() ast.TypeExpression {
+	if !p.peekTokenIs(token.COLON) {
+		return nil
+	}
+
+	p.nextToken()
+	p.nextToken()
+
+	return p.parseType()
+}
+
+// parseReturnType parses the optional "-> type" following the parameters of
+// a function
+func (p *Parser) parseReturnType() ast.TypeExpression {
+	if !p.peekTokenIs(token.ARROW) {
+		return nil
+	}
+
+	p.nextToken()
+	p.nextToken()
+
+	return p.parseType()
+}
+
+func (p *Parser) parseType() ast.TypeExpression {
+	switch p.curToken.Type {
+	case token.IDENT:
+		return &ast.NamedType{Token: p.curToken, Name: p.curToken.Literal}
+	case token.FUNCTION:
+		ft := &ast.FunctionType{Token: p.curToken}
+
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+
+		if !p.peekTokenIs(token.RPAREN) {
+			p.nextToken()
+			ft.Parameters = append(ft.Parameters, p.parseType())
+
+			for p.peekTokenIs(token.COMMA) {
+				p.nextToken()
+				p.nextToken()
+				ft.Parameters = append(ft.Parameters, p.parseType())
+			}
+		}
+
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+
+		if !p.expectPeek(token.ARROW) {
+			return nil
+		}
+
+		p.nextToken()
+		ft.Return = p.parseType()
+
+		return ft
+	default:
+		msg := fmt.Sprintf("expected a type but got %s instead", p.curToken.Type)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+}
+
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{
 		Token:    p.curToken,
@@ -436,43 +1135,247 @@ func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	return exp
 }
 
+// parseCallArguments parses the arguments of a call. Besides expressions an
+// argument is either ...array spreading its elements or name: value passing
+// the parameter called name, positional arguments can't follow those.
+func (p *Parser) parseCallArguments() []ast.Expression {
+	args := []ast.Expression{}
+
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return args
+	}
+
+	keyword := false
+	for {
+		p.nextToken()
+
+		var arg ast.Expression
+		switch {
+		case p.curTokenIs(token.ELLIPSIS):
+			tok := p.curToken
+			p.nextToken()
+			arg = &ast.SpreadExpression{Token: tok, Value: p.parseExpression(LOWEST)}
+		case p.curTokenIs(token.IDENT) && p.peekTokenIs(token.COLON):
+			name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			p.nextToken()
+			p.nextToken()
+			arg = &ast.KeywordArgument{Name: name, Value: p.parseExpression(LOWEST)}
+			keyword = true
+		default:
+			arg = p.parseExpression(LOWEST)
+			if keyword {
+				msg := fmt.Sprintf("positional argument %s follows a keyword argument", arg)
+				p.errors = append(p.errors, msg)
+				return nil
+			}
+		}
+		args = append(args, arg)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	return args
+}
+
 func (p *Parser) parseAssignExpression(left ast.Expression) ast.Expression {
 	exp := &ast.AssignExpression{
 		Token: p.curToken,
 	}
-	p.nextToken()
-	exp.Left = &ast.Identifier{
-		Token: p.curToken,
-		Value: p.curToken.Literal,
+
+	target, ok := left.(ast.Assignable)
+	if !ok {
+		msg := fmt.Sprintf("cannot assign to %s", left)
+		p.errors = append(p.errors, msg)
+		return nil
 	}
+	exp.Left = target
 
+	p.nextToken()
 	exp.Expression = p.parseExpression(LOWEST)
 
+	if exp.Token.Type != token.ASSIGN {
+		exp.Operator = strings.TrimSuffix(exp.Token.Literal, "=")
+		exp.Expression = compound(exp.Token, target, exp.Operator, exp.Expression)
+	}
+
 	return exp
 }
 
-func (p *Parser) parseCallArguments() []ast.Expression {
-	args := []ast.Expression{}
+// parseIncrementExpression parses x++ and x--, they are x += 1 and x -= 1
+func (p *Parser) parseIncrementExpression(left ast.Expression) ast.Expression {
+	exp := &ast.AssignExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal[:1],
+	}
 
-	if p.peekTokenIs(token.RPAREN) {
+	target, ok := left.(ast.Assignable)
+	if !ok {
+		msg := fmt.Sprintf("cannot assign to %s", left)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+	exp.Left = target
+
+	one := p.curToken
+	one.Type, one.Literal = token.INT, "1"
+	exp.Expression = compound(exp.Token, target, exp.Operator, &ast.IntegerLiteral{Token: one, Value: 1})
+
+	return exp
+}
+
+// compound returns the value assigned to target by a compound assignment
+// applying operator to its value and value
+func compound(tok token.Token, target ast.Assignable, operator string, value ast.Expression) ast.Expression {
+	var left ast.Expression = target
+	if ident, ok := target.(*ast.Identifier); ok {
+		left = &ast.Identifier{Token: ident.Token, Value: ident.Value}
+	}
+
+	tok.Type, tok.Literal = token.Type(operator), operator
+	return &ast.InfixExpression{
+		Token:    tok,
+		Left:     left,
+		Operator: operator,
+		Right:    value,
+	}
+}
+
+// parseExpressionList parses the comma separated expressions up to end
+func (p *Parser) parseExpressionList(end token.Type) []ast.Expression {
+	list := []ast.Expression{}
+
+	if p.peekTokenIs(end) {
 		p.nextToken()
-		return args
+		return list
 	}
 
 	p.nextToken()
-	args = append(args, p.parseExpression(LOWEST))
+	list = append(list, p.parseExpression(LOWEST))
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		args = append(args, p.parseExpression(LOWEST))
+		list = append(list, p.parseExpression(LOWEST))
 	}
 
-	if !p.expectPeek(token.RPAREN) {
+	if !p.expectPeek(end) {
 		return nil
 	}
 
-	return args
+	return list
+}
+
+func (p *Parser) parseStringLiteral() ast.Expression {
+	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{Token: p.curToken}
+
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+
+	return array
+}
+
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		key := p.parseExpression(LOWEST)
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		value := p.parseExpression(LOWEST)
+
+		hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return hash
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+
+	p.nextToken()
+	exp.Index = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return exp
+}
+
+// parseStructLiteral parses "Name { field: value, ... }", only a name can be
+// followed by a brace in an expression
+func (p *Parser) parseStructLiteral(left ast.Expression) ast.Expression {
+	name, ok := left.(*ast.Identifier)
+	if !ok {
+		p.errors = append(p.errors, fmt.Sprintf("cannot construct %s", left))
+		return nil
+	}
+	lit := &ast.StructLiteral{Token: p.curToken, Name: name}
+
+	seen := map[string]bool{}
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		field := &ast.StructField{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+		if seen[field.Name.Value] {
+			p.errors = append(p.errors, fmt.Sprintf("duplicate field %s in %s", field.Name.Value, name.Value))
+		}
+		seen[field.Name.Value] = true
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		field.Value = p.parseExpression(LOWEST)
+		lit.Fields = append(lit.Fields, field)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return lit
+}
+
+func (p *Parser) parseFieldExpression(left ast.Expression) ast.Expression {
+	exp := &ast.FieldExpression{Token: p.curToken, Left: left}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	exp.Field = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	return exp
 }
 
 func (p *Parser) parsePrefixExpression() ast.Expression {
@@ -487,6 +1390,14 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 	return expression
 }
 
+func (p *Parser) parsePostfixExpression(left ast.Expression) ast.Expression {
+	return &ast.PostfixExpression{
+		Token:    p.curToken,
+		Left:     left,
+		Operator: p.curToken.Literal,
+	}
+}
+
 func (p *Parser) parseInfixExpression(exp ast.Expression) ast.Expression {
 	expression := &ast.InfixExpression{
 		Token:    p.curToken,
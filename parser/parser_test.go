@@ -43,6 +43,29 @@ let foobar = 1234;
 	}
 }
 
+func TestConstStatement(t *testing.T) {
+	input := "const x = 5; const [a, b] = p; let y = 1;"
+	expected := "const x = 5;const [a, b] = p;let y = 1;"
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if program.String() != expected {
+		t.Errorf("expected %q, got %q", expected, program.String())
+	}
+
+	for i, expected := range []bool{true, true, false} {
+		stmt, ok := program.Statements[i].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("statement %d is not an *ast.LetStatement, got %T", i, program.Statements[i])
+		}
+		if stmt.Constant() != expected {
+			t.Errorf("statement %d: expected Constant() to be %t", i, expected)
+		}
+	}
+}
+
 func testLetStatement(t *testing.T, stmt ast.Statement, name string) bool {
 	if stmt.TokenLiteral() != "let" {
 		t.Errorf("s.TokenLiteral not 'let', got %q", stmt.TokenLiteral())
@@ -54,8 +77,8 @@ func testLetStatement(t *testing.T, stmt ast.Statement, name string) bool {
 		t.Errorf("stmt not *ast.LetStatement, got %T", stmt)
 	}
 
-	if letStmt.Name.Value != name {
-		t.Errorf("letStmt.Name.Value not '%s', got %s", name, letStmt.Name.Value)
+	if letStmt.Name.Variable() == nil || letStmt.Name.Variable().Value != name {
+		t.Errorf("letStmt.Name not '%s', got %s", name, letStmt.Name)
 		return false
 	}
 
@@ -389,6 +412,34 @@ func TestOperatorPrecedence(t *testing.T) {
 			"2 / (5 + 5)",
 			"(2 / (5 + 5))",
 		},
+		{
+			"a || b && c == d",
+			"(a || (b && (c == d)))",
+		},
+		{
+			"a <= b && c >= d % 2 || e",
+			"(((a <= b) && (c >= (d % 2))) || e)",
+		},
+		{
+			"a | b ^ c & d == e",
+			"(a | (b ^ (c & (d == e))))",
+		},
+		{
+			"a << b + c < d >> e",
+			"((a << (b + c)) < (d >> e))",
+		},
+		{
+			"~a & b >>> 1 || c",
+			"(((~a) & (b >>> 1)) || c)",
+		},
+		{
+			"x = a || b",
+			"x = (a || b);",
+		},
+		{
+			"-f(x)? + a[0]?",
+			"((-(f(x)?)) + ((a[0])?))",
+		},
 	}
 
 	for _, tt := range testCases {
@@ -559,8 +610,8 @@ func TestFunctionLiteralParsing(t *testing.T) {
 		t.Fatalf("stmt.Expression is not ast.FunctionLiteral. got %T", stmt.Expression)
 	}
 
-	testLiteralExpression(t, function.Parameters[0], "x")
-	testLiteralExpression(t, function.Parameters[1], "y")
+	testLiteralExpression(t, function.Parameters[0].Variable(), "x")
+	testLiteralExpression(t, function.Parameters[1].Variable(), "y")
 
 	if len(function.Body.Statements) != 1 {
 		t.Fatalf("function.Body.Statements does not contain 1 statement, got %d", function.Body.Statements)
@@ -632,3 +683,477 @@ func TestForExpression(t *testing.T) {
 
 	fmt.Println(exp)
 }
+
+func TestAssignExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a = 5", "a = 5;"},
+		{"a = b + 1", "a = (b + 1);"},
+		{"a = b = 1", "a = b = 1;;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+
+		if _, ok := stmt.Expression.(*ast.AssignExpression); !ok {
+			t.Fatalf("stmt.Expression is not ast.AssignExpression. got=%T", stmt.Expression)
+		}
+
+		if program.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, program.String())
+		}
+	}
+
+	p := New(lexer.New("1 = 2"))
+	p.ParseProgram()
+	if len(p.Errors()) != 1 || p.Errors()[0] != "cannot assign to 1" {
+		t.Errorf("expected an error assigning to a literal, got %v", p.Errors())
+	}
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: int = 5;", "let x: int = 5;"},
+		{"let f = fn(a: int, b) -> bool { a };", "let f = fn(a: int, b) -> bool a;"},
+		{"fn add(a: int, b: int) -> int { a + b }", "fn(a: int, b: int) -> int (a + b)"},
+		{"let apply: fn(fn(int) -> int, int) -> int = 1;", "let apply: fn(fn(int) -> int, int) -> int = 1;"},
+		{"let k: fn() -> bool = 1;", "let k: fn() -> bool = 1;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, program.String())
+		}
+	}
+
+	p := New(lexer.New("let x: 5 = 5;"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "expected a type but got INT instead" {
+		t.Errorf("expected an error for an invalid type, got %v", p.Errors())
+	}
+}
+
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"while (x < 10) { x = x + 1 }", "while ((x < 10)) {x = (x + 1);}"},
+		{"while (true) { break; continue }", "while (true) {break;continue;}"},
+		{"outer: while (true) { break outer; }", "outer: while (true) {break outer;}"},
+		{"while (true) { break\nfoo }", "while (true) {break;foo}"},
+		{"l: while (true) { continue\nl }", "l: while (true) {continue;l}"},
+		{"l: for (let i = 0; i < 1; i = i + 1) { continue l }", "l: for (let i = 0;(i < 1)i = (i + 1);){continue l;}"},
+		{"for (x in range(3)) { x }", "for (x in range(3)) {x}"},
+		{"l: for (k, v in {\"a\": 1}) { k }", "l: for (k, v in {\"a\": 1}) {k}"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, program.String())
+		}
+	}
+
+	p := New(lexer.New("l: 5"))
+	p.ParseProgram()
+	if len(p.Errors()) != 1 || p.Errors()[0] != "label l must be followed by a loop" {
+		t.Errorf("expected an error for a label without a loop, got %v", p.Errors())
+	}
+}
+
+func TestTryExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { f() } catch (e) { g(e) }", "try {f()} catch (e) {g(e)}"},
+		{"try { f() } catch { 0 } finally { g() }", "try {f()} catch {0} finally {g()}"},
+		{"let x = try { 1 } finally { 2 };", "let x = try {1} finally {2};"},
+		{`throw "oops"; 1`, `throw "oops";1`},
+		{"throw error(\"bad\", \"ValueError\")", `throw error("bad", "ValueError");`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, program.String())
+		}
+	}
+
+	p := New(lexer.New("try { 1 }"))
+	p.ParseProgram()
+	if len(p.Errors()) != 1 || p.Errors()[0] != "try at 1:1 must be followed by catch or finally" {
+		t.Errorf("expected an error for a try without clauses, got %v", p.Errors())
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match (x) { 0 => a, -1.5 => b, _ => c }", "match (x) {0 => {a}, (-1.5) => {b}, _ => {c}}"},
+		{"match (x) { n if n > 10 => { n } true => 1 }", "match (x) {n if (n > 10) => {n}, true => {1}}"},
+		{"match (xs) { [] => 0, [first, ...rest] => first, [_, ..._] => 1, }", "match (xs) {[] => {0}, [first, ...rest] => {first}, [_, ..._] => {1}}"},
+		{`match (h) { {"k": v, 1: [x]} => v + x }`, `match (h) {{"k": v, 1: [x]} => {(v + x)}}`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, program.String())
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"match (x) { a + 1 => 1 }", "expected next token to be => but got + instead"},
+		{"match (x) { 1 => a 2 => b }", "expected next token to be , but got INT instead"},
+		{"match (x) { fn => 1 }", "expected a pattern but got FUNCTION instead"},
+	}
+
+	for _, tt := range errors {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("expected error %q, got %v", tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestDestructuring(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b, ...rest] = arr;", "let [a, b, ...rest] = arr;"},
+		{"let {name, age} = person;", "let {name, age} = person;"},
+		{`let {name: n, "x": [x, _]} = p;`, `let {name: n, "x": [x, _]} = p;`},
+		{"let f = fn({x, y}, [first, ..._], z: int) { x };", "let f = fn({x, y}, [first, ..._], z: int) x;"},
+		{"fn dist({x, y}) { x * y }", "fn({x, y}) (x * y)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, program.String())
+		}
+	}
+
+	program := New(lexer.New("let [a, b] = arr;")).ParseProgram()
+	decl := program.Statements[0].(*ast.LetStatement).Name
+	if _, ok := decl.Pattern.(*ast.ArrayPattern); !ok || decl.Variable() != nil {
+		t.Errorf("expected the let statement to destructure an array, got %T", decl.Pattern)
+	}
+
+	p := New(lexer.New("let 5 = x;"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "expected a name or a pattern but got INT instead" {
+		t.Errorf("expected an error for a let without a name, got %v", p.Errors())
+	}
+}
+
+func TestParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let f = fn(a, b = 2) { a + b };", "let f = fn(a, b = 2) (a + b);"},
+		{"let f = fn(a: int = 1, ...rest) { a };", "let f = fn(a: int = 1, ...rest) a;"},
+		{"f(...xs, b: 3, c: a + 1)", "f(...xs, b: 3, c: (a + 1))"},
+		{"f(1, ...[2, 3])", "f(1, ...[2, 3])"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, program.String())
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(...rest, a) { a }", "rest parameter rest must be the last parameter"},
+		{"fn(a = 1, b) { b }", "parameter b without a default follows a parameter with one"},
+		{"f(a: 1, 2)", "positional argument 2 follows a keyword argument"},
+	}
+
+	for _, tt := range errors {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("expected error %q for %q, got %v", tt.expected, tt.input, p.Errors())
+		}
+	}
+}
+
+func TestStructs(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"struct Point { x, y }", "struct Point { x, y }"},
+		{"struct Empty {}", "struct Empty {  }"},
+		{"struct P {\n  x,\n  y,\n}; 1", "struct P { x, y }1"},
+		{"let p = Point { x: 1, y: a + 1 };", "let p = Point {x: 1, y: (a + 1)};"},
+		{"Point { x: 1 }.x + 1", "((Point {x: 1}.x) + 1)"},
+		{"if (a) { b }", "ifa b"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, program.String())
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"struct P { x, x }", "duplicate field x in struct P"},
+		{"P { x: 1, x: 2 }", "duplicate field x in P"},
+		{"f() { x: 1 }", "cannot construct f()"},
+	}
+
+	for _, tt := range errors {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("expected error %q for %q, got %v", tt.expected, tt.input, p.Errors())
+		}
+	}
+}
+
+func TestImplStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"impl P { fn get(self) { self.x } }", "impl P { fn get(self) (self.x) }"},
+		{"impl P { fn a(s, n = 1) { n } fn b(s) -> int { 1 } }", "impl P { fn a(s, n = 1) n fn b(s) -> int 1 }"},
+		{"impl P {}", "impl P {  }"},
+		{"p.get(1)", "(p.get)(1)"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, program.String())
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"impl P { fn a(s) { 1 } fn a(s) { 2 } }", "duplicate method a in impl P"},
+		{"impl P { fn a() { 1 } }", "method a must take its receiver as first parameter"},
+		{"impl P { fn a(...s) { 1 } }", "method a must take its receiver as first parameter"},
+		{"impl P { let a = 1; }", "expected next token to be FUNCTION but got LET instead"},
+	}
+
+	for _, tt := range errors {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("expected error %q for %q, got %v", tt.expected, tt.input, p.Errors())
+		}
+	}
+}
+
+func TestCompoundAssignment(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  string
+		desugared string
+	}{
+		{"x += 1", "x += 1;", "(x + 1)"},
+		{"x -= a * b", "x -= (a * b);", "(x - (a * b))"},
+		{"x *= 2", "x *= 2;", "(x * 2)"},
+		{"x /= y", "x /= y;", "(x / y)"},
+		{"x %= 3", "x %= 3;", "(x % 3)"},
+		{"i++", "i += 1;", "(i + 1)"},
+		{"i--", "i -= 1;", "(i - 1)"},
+		{"a + i++", "(a + i += 1;)", ""},
+		{"a[i] = 1", "(a[i]) = 1;", "1"},
+		{"h.name = n", "(h.name) = n;", "n"},
+		{"a[f()] *= 2", "(a[f()]) *= 2;", "((a[f()]) * 2)"},
+		{"p.x.y += a.b", "((p.x).y) += (a.b);", "(((p.x).y) + (a.b))"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, program.String())
+		}
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if exp, ok := stmt.Expression.(*ast.AssignExpression); ok && exp.Expression.String() != tt.desugared {
+			t.Errorf("expected %q to be desugared to %q, got %q", tt.input, tt.desugared, exp.Expression.String())
+		}
+	}
+
+	p := New(lexer.New("f() += 1"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "cannot assign to f()" {
+		t.Errorf("expected an error for an assignment to a call, got %v", p.Errors())
+	}
+}
+
+func TestCollectionLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"hello world"`, `"hello world"`},
+		{"3.14 * -.5", "(3.14 * (-0.5))"},
+		{"1e3 + 2", "(1000.0 + 2)"},
+		{"[]", "[]"},
+		{"[1, 2 * 2, 3 + 3]", "[1, (2 * 2), (3 + 3)]"},
+		{"{}", "{}"},
+		{`{"one": 1, "two": 1 + 1}`, `{"one": 1, "two": (1 + 1)}`},
+		{"a[1 + 1]", "(a[(1 + 1)])"},
+		{"a * [1, 2][b * c] * d", "((a * ([1, 2][(b * c)])) * d)"},
+		{"add(a * b[2], b[1])", "add((a * (b[2])), (b[1]))"},
+		{"f(x)[0]", "(f(x)[0])"},
+		{"a.b[0].c", "(((a.b)[0]).c)"},
+		{"-p.x * 2", "((-(p.x)) * 2)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestNumberLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		value    int64
+		expected string
+	}{
+		{"0x1F", 31, "0x1F"},
+		{"0o17", 15, "0o17"},
+		{"0b1010", 10, "0b1010"},
+		{"1_000_000", 1000000, "1_000_000"},
+		{"0755", 755, "0755"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		literal := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IntegerLiteral)
+		if literal.Value != tt.value {
+			t.Errorf("expected %d, got %d", tt.value, literal.Value)
+		}
+		if literal.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, literal.String())
+		}
+	}
+
+	bigs := []struct {
+		input    string
+		expected string
+	}{
+		{"9223372036854775808", "9223372036854775808"},
+		{"0x1_0000_0000_0000_0000", "18446744073709551616"},
+		{"0700000000000000000000000", "700000000000000000000000"},
+	}
+
+	for _, tt := range bigs {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		literal, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.BigIntegerLiteral)
+		if !ok {
+			t.Fatalf("expected *ast.BigIntegerLiteral, got %T", program.Statements[0].(*ast.ExpressionStatement).Expression)
+		}
+		if literal.Value.String() != tt.expected {
+			t.Errorf("expected %s, got %s", tt.expected, literal.Value)
+		}
+		if literal.String() != tt.input {
+			t.Errorf("expected %q, got %q", tt.input, literal.String())
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 0x;", `malformed number "0x" at 1:9: hexadecimal literal has no digits`},
+		{"1 + 12abc", `malformed number "12abc" at 1:5: invalid digit 'a' in decimal literal`},
+		{"0x1F.5", `malformed number "0x1F.5" at 1:1: hexadecimal literal cannot have a fractional part`},
+		{"let y = 1 @ 2;", `illegal token "@" at 1:11`},
+	}
+
+	for _, tt := range errors {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("expected error %q, got %v", tt.expected, p.Errors())
+		}
+	}
+}
@@ -38,6 +38,14 @@ func Start(in io.Reader, out io.Writer) {
 		if evaluated != nil {
 			fmt.Fprintln(out, evaluated.Inspect())
 		}
+		if errObj, ok := evaluated.(*object.Error); ok {
+			for _, call := range errObj.Stack {
+				fmt.Fprintf(out, "\tat %s\n", call)
+			}
+			if errObj.Trace != "" {
+				fmt.Fprintln(out, errObj.Trace)
+			}
+		}
 	}
 }
 
@@ -0,0 +1,429 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rumpl/monkey-lang/ast"
+	"github.com/rumpl/monkey-lang/object"
+	"github.com/rumpl/monkey-lang/token"
+)
+
+// Severity is how bad a diagnostic is
+type Severity int
+
+const (
+	Warning Severity = iota
+	Error
+)
+
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// Diagnostic is a problem found in the program
+type Diagnostic struct {
+	Pos      token.Position
+	Severity Severity
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s: %s", d.Pos, d.Severity, d.Message)
+}
+
+type symbol struct {
+	name string
+	pos  token.Position
+	// reportUnused tells if the symbol should be reported when it is unused,
+	// parameters and named functions are not
+	reportUnused bool
+	used         bool
+	// constant is true for the variables declared by a const statement
+	constant bool
+}
+
+type scope struct {
+	parent *scope
+	// function is true for the scope of a function body, the top level scope
+	// of the program has no parent
+	function bool
+	symbols  map[string]*symbol
+}
+
+func newScope(parent *scope, function bool) *scope {
+	return &scope{parent: parent, function: function, symbols: map[string]*symbol{}}
+}
+
+// pending is a function body waiting to be resolved
+type pending struct {
+	scope  *scope
+	params []*ast.Declaration
+	body   *ast.BlockStatement
+}
+
+// Resolver binds identifiers to the variables they refer to
+type Resolver struct {
+	scope *scope
+	// loops holds the labels of the loops around the current statement, an
+	// unlabeled loop has an empty label
+	loops       []string
+	symbols     []*symbol
+	pending     []pending
+	diagnostics []Diagnostic
+}
+
+// Resolve annotates every identifier in program with its binding and
+// returns the problems found, sorted by position
+func Resolve(program *ast.Program) []Diagnostic {
+	r := &Resolver{}
+	return r.Resolve(program)
+}
+
+// Resolve annotates every identifier in program with its binding and
+// returns the problems found, sorted by position
+func (r *Resolver) Resolve(program *ast.Program) []Diagnostic {
+	r.scope = newScope(nil, false)
+	r.statements(program.Statements)
+
+	// Function bodies are resolved once the scopes around them are complete.
+	// A function only runs when it is called, by then the variables defined
+	// after it in an enclosing scope exist.
+	for len(r.pending) > 0 {
+		p := r.pending[0]
+		r.pending = r.pending[1:]
+		r.function(p)
+	}
+
+	for _, s := range r.symbols {
+		if s.reportUnused && !s.used {
+			r.warning(s.pos, "%s declared but not used", s.name)
+		}
+	}
+
+	sort.SliceStable(r.diagnostics, func(i, j int) bool {
+		a, b := r.diagnostics[i].Pos, r.diagnostics[j].Pos
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+
+	return r.diagnostics
+}
+
+func (r *Resolver) errorf(pos token.Position, format string, a ...interface{}) {
+	r.diagnostics = append(r.diagnostics, Diagnostic{Pos: pos, Severity: Error, Message: fmt.Sprintf(format, a...)})
+}
+
+func (r *Resolver) warning(pos token.Position, format string, a ...interface{}) {
+	r.diagnostics = append(r.diagnostics, Diagnostic{Pos: pos, Severity: Warning, Message: fmt.Sprintf(format, a...)})
+}
+
+func (r *Resolver) declare(name string, pos token.Position, reportUnused bool) {
+	for s := r.scope.parent; s != nil; s = s.parent {
+		if prev, ok := s.symbols[name]; ok {
+			r.warning(pos, "%s shadows the variable declared at %s", name, prev.pos)
+			break
+		}
+	}
+
+	sym := &symbol{name: name, pos: pos, reportUnused: reportUnused}
+	r.scope.symbols[name] = sym
+	r.symbols = append(r.symbols, sym)
+}
+
+// lookup finds the variable name refers to from the current scope
+func (r *Resolver) lookup(name string) (*symbol, *ast.Binding) {
+	depth := 0
+	for s := r.scope; s != nil; s = s.parent {
+		if sym, ok := s.symbols[name]; ok {
+			binding := &ast.Binding{Depth: depth, Pos: sym.pos}
+			switch {
+			case s.parent == nil:
+				binding.Kind = ast.Global
+			case depth == 0:
+				binding.Kind = ast.Local
+			default:
+				binding.Kind = ast.Free
+			}
+			return sym, binding
+		}
+
+		if s.function {
+			depth++
+		}
+	}
+
+	if object.IsBuiltin(name) {
+		return nil, &ast.Binding{Kind: ast.Builtin}
+	}
+
+	return nil, &ast.Binding{Kind: ast.Unresolved}
+}
+
+func (r *Resolver) statements(stmts []ast.Statement) {
+	// Named functions can be called before their declaration
+	for _, stmt := range stmts {
+		if fs, ok := stmt.(*ast.FunctionStatement); ok {
+			r.declare(fs.Name, fs.Pos(), false)
+		}
+	}
+
+	for _, stmt := range stmts {
+		r.node(stmt)
+	}
+}
+
+func (r *Resolver) function(p pending) {
+	outer, outerLoops := r.scope, r.loops
+	r.scope = newScope(p.scope, true)
+	// break and continue can't leave a function
+	r.loops = nil
+
+	for _, param := range p.params {
+		// A default can use the parameters before it
+		if param.Default != nil {
+			r.node(param.Default)
+		}
+		for _, name := range ast.PatternNames(param.Pattern) {
+			r.declare(name.Value, name.Pos(), false)
+			_, name.Binding = r.lookup(name.Value)
+		}
+	}
+	r.statements(p.body.Statements)
+
+	r.scope, r.loops = outer, outerLoops
+}
+
+// loop resolves the body of a loop labeled label
+func (r *Resolver) loop(label *ast.Identifier, body func()) {
+	name := ""
+	if label != nil {
+		name = label.Value
+	}
+
+	r.loops = append(r.loops, name)
+	body()
+	r.loops = r.loops[:len(r.loops)-1]
+}
+
+// exhaustive warns about a match on booleans that doesn't handle both of
+// them. Only arms without a guard count, an arm binding or ignoring the value
+// handles every value.
+func (r *Resolver) exhaustive(node *ast.MatchExpression) {
+	boolean := false
+	covered := map[bool]bool{}
+
+	for _, arm := range node.Arms {
+		switch pattern := arm.Pattern.(type) {
+		case *ast.Boolean:
+			boolean = true
+			if arm.Guard == nil {
+				covered[pattern.Value] = true
+			}
+		case *ast.Identifier, *ast.WildcardPattern:
+			if arm.Guard == nil {
+				return
+			}
+		}
+	}
+
+	if !boolean {
+		return
+	}
+
+	for _, value := range []bool{true, false} {
+		if !covered[value] {
+			r.warning(node.Pos(), "match is not exhaustive, %t is not handled", value)
+		}
+	}
+}
+
+// jump checks that a break or continue has a loop to jump to
+func (r *Resolver) jump(node ast.Node, label *ast.Identifier) {
+	if label == nil {
+		if len(r.loops) == 0 {
+			r.errorf(node.Pos(), "%s outside of a loop", node.TokenLiteral())
+		}
+		return
+	}
+
+	for _, l := range r.loops {
+		if l == label.Value {
+			return
+		}
+	}
+	r.errorf(label.Pos(), "%s to unknown label %s", node.TokenLiteral(), label.Value)
+}
+
+func (r *Resolver) node(node ast.Node) {
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		r.node(node.Value)
+		for _, name := range ast.PatternNames(node.Name.Pattern) {
+			if prev, ok := r.scope.symbols[name.Value]; ok && prev.constant {
+				r.errorf(name.Pos(), "cannot redeclare constant %s declared at %s", name.Value, prev.pos)
+			}
+			r.declare(name.Value, name.Pos(), true)
+			r.scope.symbols[name.Value].constant = node.Constant()
+			_, name.Binding = r.lookup(name.Value)
+		}
+	case *ast.ReturnStatement:
+		r.node(node.ReturnValue)
+	case *ast.ExpressionStatement:
+		r.node(node.Expression)
+	case *ast.BlockStatement:
+		// The variables declared in the body of an if, a loop or a try are
+		// only visible in it
+		outer := r.scope
+		r.scope = newScope(outer, false)
+		r.statements(node.Statements)
+		r.scope = outer
+	case *ast.StructStatement:
+		r.declare(node.Name.Value, node.Name.Pos(), false)
+		_, node.Name.Binding = r.lookup(node.Name.Value)
+	case *ast.ImplStatement:
+		r.node(node.Name)
+		for _, m := range node.Methods {
+			r.pending = append(r.pending, pending{scope: r.scope, params: m.Parameters, body: m.Body})
+		}
+	case *ast.FunctionStatement:
+		r.pending = append(r.pending, pending{scope: r.scope, params: node.Parameters, body: node.Body})
+	case *ast.FunctionLiteral:
+		r.pending = append(r.pending, pending{scope: r.scope, params: node.Parameters, body: node.Body})
+	case *ast.Identifier:
+		sym, binding := r.lookup(node.Value)
+		node.Binding = binding
+		if binding.Kind == ast.Builtin {
+			return
+		}
+		if sym == nil {
+			r.errorf(node.Pos(), "undefined: %s", node.Value)
+			return
+		}
+		sym.used = true
+	case *ast.AssignExpression:
+		r.node(node.Expression)
+		left, ok := node.Left.(*ast.Identifier)
+		if !ok {
+			// Elements and fields are assigned to in place, the array, hash
+			// or index used is read
+			r.node(node.Left)
+			return
+		}
+		sym, binding := r.lookup(left.Value)
+		left.Binding = binding
+		if sym == nil {
+			r.errorf(left.Pos(), "assignment to undeclared variable %s", left.Value)
+		} else if sym.constant {
+			r.errorf(left.Pos(), "cannot assign to constant %s declared at %s", left.Value, sym.pos)
+		}
+	case *ast.PrefixExpression:
+		r.node(node.Right)
+	case *ast.PostfixExpression:
+		r.node(node.Left)
+	case *ast.InfixExpression:
+		r.node(node.Left)
+		r.node(node.Right)
+	case *ast.IfExpression:
+		r.node(node.Condition)
+		r.node(node.Consequence)
+		if node.Alternative != nil {
+			r.node(node.Alternative)
+		}
+	case *ast.CallExpression:
+		r.node(node.Function)
+		for _, arg := range node.Arguments {
+			r.node(arg)
+		}
+	case *ast.SpreadExpression:
+		r.node(node.Value)
+	case *ast.KeywordArgument:
+		// The name is a parameter of the callee, not a variable
+		r.node(node.Value)
+	case *ast.ForExpression:
+		outer := r.scope
+		r.scope = newScope(outer, false)
+		r.node(node.Initial)
+		r.node(node.StopCondition)
+		r.loop(node.Label, func() {
+			r.node(node.Increment)
+			r.node(node.Statements)
+		})
+		r.scope = outer
+	case *ast.ForInExpression:
+		r.node(node.Iterable)
+		outer := r.scope
+		r.scope = newScope(outer, false)
+		for _, name := range node.Names {
+			r.declare(name.Value, name.Pos(), true)
+			_, name.Binding = r.lookup(name.Value)
+		}
+		r.loop(node.Label, func() { r.node(node.Body) })
+		r.scope = outer
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			r.node(el)
+		}
+	case *ast.HashLiteral:
+		for _, pair := range node.Pairs {
+			r.node(pair.Key)
+			r.node(pair.Value)
+		}
+	case *ast.IndexExpression:
+		r.node(node.Left)
+		r.node(node.Index)
+	case *ast.FieldExpression:
+		r.node(node.Left)
+	case *ast.StructLiteral:
+		r.node(node.Name)
+		for _, field := range node.Fields {
+			r.node(field.Value)
+		}
+	case *ast.WhileExpression:
+		r.node(node.Condition)
+		r.loop(node.Label, func() { r.node(node.Body) })
+	case *ast.MatchExpression:
+		r.node(node.Subject)
+		for _, arm := range node.Arms {
+			outer := r.scope
+			r.scope = newScope(outer, false)
+			for _, name := range ast.PatternNames(arm.Pattern) {
+				r.declare(name.Value, name.Pos(), true)
+				_, name.Binding = r.lookup(name.Value)
+			}
+			if arm.Guard != nil {
+				r.node(arm.Guard)
+			}
+			r.node(arm.Body)
+			r.scope = outer
+		}
+		r.exhaustive(node)
+	case *ast.ThrowStatement:
+		r.node(node.Value)
+	case *ast.TryExpression:
+		r.node(node.Body)
+		if node.Catch != nil {
+			outer := r.scope
+			r.scope = newScope(outer, false)
+			if node.Param != nil {
+				// Catching an error without looking at it is common
+				r.declare(node.Param.Value, node.Param.Pos(), false)
+				_, node.Param.Binding = r.lookup(node.Param.Value)
+			}
+			r.node(node.Catch)
+			r.scope = outer
+		}
+		if node.Finally != nil {
+			r.node(node.Finally)
+		}
+	case *ast.BreakStatement:
+		r.jump(node, node.Label)
+	case *ast.ContinueStatement:
+		r.jump(node, node.Label)
+	}
+}
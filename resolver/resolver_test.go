@@ -0,0 +1,136 @@
+package resolver
+
+import (
+	"testing"
+
+	"github.com/rumpl/monkey-lang/ast"
+	"github.com/rumpl/monkey-lang/lexer"
+	"github.com/rumpl/monkey-lang/parser"
+)
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let a = 1; a;", nil},
+		{"a;", []string{"1:1: error: undefined: a"}},
+		{"a; let a = 1; a;", []string{"1:1: error: undefined: a"}},
+		{"let a = 1;", []string{"1:5: warning: a declared but not used"}},
+		{"let a = 1; b = a;", []string{"1:12: error: assignment to undeclared variable b"}},
+		{"let a = 1; a = 2;", []string{"1:5: warning: a declared but not used"}},
+		{"let a = 1; let f = fn(a) { a }; f(a);", []string{"1:23: warning: a shadows the variable declared at 1:5"}},
+		{"let f = fn() { g() }; let g = fn() { 1 }; f();", nil},
+		{"let f = fn() { f() }; f();", nil},
+		{"fn main() { test() } fn test() { 1 }", nil},
+		{"let f = fn(x) { y }; f(1);", []string{"1:17: error: undefined: y"}},
+		{"for (let i = 0; i < 10; i = i + 1) { i }; i;", []string{"1:43: error: undefined: i"}},
+		{"for (let i = 0; i < 10; i = i + 1) { 1 }", nil},
+		{"for (let i = 0; true; 1) { 1 }", []string{"1:10: warning: i declared but not used"}},
+		{"if (true) { let a = 1; } a;", []string{"1:17: warning: a declared but not used", "1:26: error: undefined: a"}},
+		{"let a = 1; if (a) { let a = 2; a }", []string{"1:25: warning: a shadows the variable declared at 1:5"}},
+		{"const a = [1]; a[0] = 2; a.x = 3;", nil},
+		{"b[0] = 1;", []string{"1:1: error: undefined: b"}},
+		{"const a = 1; a = 2; a;", []string{"1:14: error: cannot assign to constant a declared at 1:7"}},
+		{"const a = 1; let a = 2; a;", []string{"1:7: warning: a declared but not used", "1:18: error: cannot redeclare constant a declared at 1:7"}},
+		{"const a = 1; if (a) { let a = 2; a = 3; a }", []string{"1:27: warning: a shadows the variable declared at 1:7"}},
+		{"while (true) { break; }", nil},
+		{"break;", []string{"1:1: error: break outside of a loop"}},
+		{"while (true) { let f = fn() { continue; }; f(); }", []string{"1:31: error: continue outside of a loop"}},
+		{"l: while (true) { while (true) { break l; } }", nil},
+		{"while (true) { continue l; }", []string{"1:25: error: continue to unknown label l"}},
+		{"for (x in range(3)) { len([x]) }", nil},
+		{"for (k, v in {}) { k }; v;", []string{"1:9: warning: v declared but not used", "1:25: error: undefined: v"}},
+		{"try { throw 1; } catch (e) { 2 } finally { 3 }", nil},
+		{"try { 1 } catch (e) { e }; e;", []string{"1:28: error: undefined: e"}},
+		{"match (1) { [x, ...xs] if x > 0 => xs, x => x }; xs;", []string{"1:50: error: undefined: xs"}},
+		{"match (1) { [x, y] => x }", []string{"1:17: warning: y declared but not used"}},
+		{"match (true) { true => 1 }", []string{"1:1: warning: match is not exhaustive, false is not handled"}},
+		{"match (true) { true => 1, false if true => 2 }", []string{"1:1: warning: match is not exhaustive, false is not handled"}},
+		{"match (true) { true => 1, false => 2 }", nil},
+		{"match (true) { true => 1, _ => 2 }", nil},
+		{"let [a, ...b] = [1]; a + c;", []string{"1:12: warning: b declared but not used", "1:26: error: undefined: c"}},
+		{"let f = fn({x, y}) { x }; f(1);", nil},
+		{"let f = fn(a, b = a, ...c) { b + c }; f(...[1], b: 2);", nil},
+		{"let f = fn(a = b, b = 1) { a + b }; f();", []string{"1:16: error: undefined: b"}},
+		{"struct P { x } let p = P { x: 1 }; p.x;", nil},
+		{"struct P { x }", nil},
+		{"struct P { x } impl P { fn get(self) { self.x + y } }", []string{"1:49: error: undefined: y"}},
+		{"impl P { fn get(self) { 1 } }", []string{"1:6: error: undefined: P"}},
+		{"let p = P { x: y };", []string{"1:5: warning: p declared but not used", "1:9: error: undefined: P", "1:16: error: undefined: y"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			diagnostics := Resolve(parse(t, tt.input))
+
+			if len(diagnostics) != len(tt.expected) {
+				t.Fatalf("expected %d diagnostics, got %v", len(tt.expected), diagnostics)
+			}
+
+			for i, d := range diagnostics {
+				if d.String() != tt.expected[i] {
+					t.Errorf("expected %q, got %q", tt.expected[i], d.String())
+				}
+			}
+		})
+	}
+}
+
+func TestBindings(t *testing.T) {
+	input := `
+let a = 1;
+let f = fn(b) {
+	let c = 2;
+	return fn(d) { a + b + c + d + e };
+};
+f(a)(a);
+`
+	program := parse(t, input)
+	Resolve(program)
+
+	expected := map[string]ast.Binding{
+		"a": {Kind: ast.Global, Depth: 2},
+		"b": {Kind: ast.Free, Depth: 1},
+		"c": {Kind: ast.Free, Depth: 1},
+		"d": {Kind: ast.Local, Depth: 0},
+		"e": {Kind: ast.Unresolved},
+	}
+
+	let := program.Statements[1].(*ast.LetStatement)
+	outer := let.Value.(*ast.FunctionLiteral)
+	inner := outer.Body.Statements[1].(*ast.ReturnStatement).ReturnValue.(*ast.FunctionLiteral)
+	body := inner.Body.Statements[0].(*ast.ExpressionStatement).Expression
+
+	seen := 0
+	ast.Modify(body, func(node ast.Node) ast.Node {
+		ident, ok := node.(*ast.Identifier)
+		if !ok {
+			return node
+		}
+		seen++
+
+		want := expected[ident.Value]
+		if ident.Binding == nil {
+			t.Fatalf("%s has no binding", ident.Value)
+		}
+		if ident.Binding.Kind != want.Kind || ident.Binding.Depth != want.Depth {
+			t.Errorf("%s: expected %s at depth %d, got %s at depth %d",
+				ident.Value, want.Kind, want.Depth, ident.Binding.Kind, ident.Binding.Depth)
+		}
+		return node
+	})
+
+	if seen != len(expected) {
+		t.Errorf("expected %d identifiers, saw %d", len(expected), seen)
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
@@ -0,0 +1,596 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rumpl/monkey-lang/ast"
+	"github.com/rumpl/monkey-lang/token"
+)
+
+// Error is a type error found in the program
+type Error struct {
+	Pos     token.Position
+	Message string
+}
+
+func (e Error) String() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+}
+
+// Info holds the types found by the checker
+type Info struct {
+	// Types maps expressions, declared identifiers and functions to their
+	// type
+	Types map[ast.Node]Type
+}
+
+// TypeOf returns the type of node, Any when the checker didn't see it
+func (i *Info) TypeOf(node ast.Node) Type {
+	if t, ok := i.Types[node]; ok {
+		return t
+	}
+	return Any
+}
+
+type scope struct {
+	parent *scope
+	vars   map[string]Type
+	// inferred are the declarations of the unannotated variables, their type
+	// widens when they are assigned a value of another type
+	inferred map[string]*ast.Identifier
+}
+
+func (s *scope) lookup(name string) (Type, bool) {
+	if s = s.find(name); s != nil {
+		return s.vars[name], true
+	}
+	return nil, false
+}
+
+// find returns the scope name is declared in, nil when it is not declared
+func (s *scope) find(name string) *scope {
+	for ; s != nil; s = s.parent {
+		if _, ok := s.vars[name]; ok {
+			return s
+		}
+	}
+	return nil
+}
+
+// frame is the function whose body is being checked
+type frame struct {
+	// declared is the annotated return type, nil when there is none
+	declared Type
+	returns  []Type
+}
+
+// Checker checks that the values of a program are used according to their
+// types. Unannotated parameters are of type Any and are not checked,
+// unannotated variables get the type of the values they are given.
+type Checker struct {
+	info  *Info
+	scope *scope
+	frame *frame
+	// unchecked holds the bodies of the named functions that were not
+	// checked yet, they are checked the first time they are called so that
+	// the return type of the functions is inferred before it is used
+	unchecked map[*Function]func()
+	errors    []Error
+}
+
+// Check type checks program
+func Check(program *ast.Program) (*Info, []Error) {
+	c := &Checker{}
+	return c.Check(program)
+}
+
+// Check type checks program
+func (c *Checker) Check(program *ast.Program) (*Info, []Error) {
+	c.info = &Info{Types: map[ast.Node]Type{}}
+	c.scope = &scope{vars: map[string]Type{}}
+	c.unchecked = map[*Function]func(){}
+
+	c.statements(program.Statements)
+
+	sort.SliceStable(c.errors, func(i, j int) bool {
+		a, b := c.errors[i].Pos, c.errors[j].Pos
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+
+	return c.info, c.errors
+}
+
+func (c *Checker) errorf(pos token.Position, format string, a ...interface{}) {
+	c.errors = append(c.errors, Error{Pos: pos, Message: fmt.Sprintf(format, a...)})
+}
+
+func (c *Checker) record(node ast.Node, t Type) Type {
+	c.info.Types[node] = t
+	return t
+}
+
+func (c *Checker) annotation(node ast.Node, annotation ast.TypeExpression) Type {
+	t, ok := FromAnnotation(annotation)
+	if !ok {
+		c.errorf(node.Pos(), "unknown type %s", annotation)
+	}
+	return t
+}
+
+// signature returns the type of a function from its annotations
+func (c *Checker) signature(params []*ast.Declaration, ret ast.TypeExpression, node ast.Node) *Function {
+	f := &Function{}
+	for _, p := range params {
+		f.Params = append(f.Params, c.annotation(p, p.Type))
+	}
+	f.Return = c.annotation(node, ret)
+	return f
+}
+
+// statements checks a list of statements and returns the type of its value
+func (c *Checker) statements(stmts []ast.Statement) Type {
+	// Named functions can be called before their declaration
+	for _, stmt := range stmts {
+		if fs, ok := stmt.(*ast.FunctionStatement); ok {
+			f := c.signature(fs.Parameters, fs.ReturnType, fs)
+			c.scope.vars[fs.Name] = c.record(fs, f)
+			if optionalParameters(fs.Parameters) {
+				c.scope.vars[fs.Name] = Any
+			}
+
+			declared := c.scope
+			c.unchecked[f] = func() {
+				outer := c.scope
+				c.scope = declared
+				c.function(f, fs.ReturnType != nil, fs.Parameters, fs.Body)
+				c.scope = outer
+			}
+		}
+	}
+
+	var result Type = Any
+	for _, stmt := range stmts {
+		result = c.statement(stmt)
+	}
+
+	return result
+}
+
+func (c *Checker) statement(stmt ast.Statement) Type {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		value := c.expression(stmt.Value)
+		id := stmt.Name.Variable()
+		if id == nil {
+			c.declarePattern(stmt.Name.Pattern)
+			return Any
+		}
+		t := value
+		if stmt.Name.Type != nil {
+			t = c.annotation(stmt.Name, stmt.Name.Type)
+			if !Consistent(value, t) {
+				c.errorf(stmt.Value.Pos(), "cannot use %s as %s in let statement", value, t)
+			}
+			delete(c.scope.inferred, id.Value)
+		} else {
+			if c.scope.inferred == nil {
+				c.scope.inferred = map[string]*ast.Identifier{}
+			}
+			c.scope.inferred[id.Value] = id
+		}
+		c.scope.vars[id.Value] = c.record(id, t)
+		return Any
+	case *ast.ReturnStatement:
+		t := c.expression(stmt.ReturnValue)
+		if c.frame != nil {
+			if c.frame.declared != nil && !Consistent(t, c.frame.declared) {
+				c.errorf(stmt.ReturnValue.Pos(), "cannot use %s as %s in return statement", t, c.frame.declared)
+			}
+			c.frame.returns = append(c.frame.returns, t)
+		}
+		return t
+	case *ast.ExpressionStatement:
+		return c.expression(stmt.Expression)
+	case *ast.FunctionStatement:
+		c.checkBody(c.info.Types[stmt].(*Function))
+		return Any
+	case *ast.StructStatement:
+		c.declarePattern(stmt.Name)
+		return Any
+	case *ast.ImplStatement:
+		c.expression(stmt.Name)
+		for _, m := range stmt.Methods {
+			c.function(c.signature(m.Parameters, m.ReturnType, m), m.ReturnType != nil, m.Parameters, m.Body)
+		}
+		return Any
+	case *ast.ThrowStatement:
+		c.expression(stmt.Value)
+		return Any
+	}
+
+	return Any
+}
+
+// checkBody checks the body of the named function of type f if it was not
+// checked yet
+func (c *Checker) checkBody(f *Function) {
+	check, ok := c.unchecked[f]
+	if !ok {
+		return
+	}
+
+	// Recursive calls use the type the function has so far
+	delete(c.unchecked, f)
+	check()
+}
+
+// function checks the body of a function of type f, the return type of f is
+// inferred from the body when it is not annotated
+func (c *Checker) function(f *Function, annotated bool, params []*ast.Declaration, body *ast.BlockStatement) {
+	outer, outerFrame := c.scope, c.frame
+	c.scope = &scope{parent: outer, vars: map[string]Type{}}
+	c.frame = &frame{}
+	if annotated {
+		c.frame.declared = f.Return
+	}
+
+	for i, p := range params {
+		if p.Default != nil {
+			t := c.expression(p.Default)
+			if !Consistent(t, f.Params[i]) {
+				c.errorf(p.Default.Pos(), "cannot use %s as %s in default of %s", t, f.Params[i], p.Pattern)
+			}
+		}
+		id := p.Variable()
+		switch {
+		case id == nil:
+			c.declarePattern(p.Pattern)
+		case p.Rest:
+			// The array of the arguments left
+			c.scope.vars[id.Value] = c.record(id, Any)
+		default:
+			c.scope.vars[id.Value] = c.record(id, f.Params[i])
+		}
+	}
+
+	result := c.statements(body.Statements)
+
+	if annotated {
+		// The value of the last expression is returned too
+		if n := len(body.Statements); n > 0 {
+			if es, ok := body.Statements[n-1].(*ast.ExpressionStatement); ok && !Consistent(result, f.Return) {
+				c.errorf(es.Pos(), "cannot use %s as %s in return statement", result, f.Return)
+			}
+		}
+	} else {
+		t := result
+		for _, r := range c.frame.returns {
+			t = join(t, r)
+		}
+		f.Return = t
+	}
+
+	c.scope, c.frame = outer, outerFrame
+}
+
+func (c *Checker) expression(exp ast.Expression) Type {
+	if exp == nil {
+		return Any
+	}
+
+	return c.record(exp, c.expressionType(exp))
+}
+
+func (c *Checker) expressionType(exp ast.Expression) Type {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral, *ast.BigIntegerLiteral:
+		return Int
+	case *ast.FloatLiteral:
+		return Float
+	case *ast.Boolean:
+		return Bool
+	case *ast.Identifier:
+		// Undefined variables are reported by the resolver
+		if t, ok := c.scope.lookup(exp.Value); ok {
+			return t
+		}
+		if f, ok := builtins()[exp.Value]; ok {
+			return f
+		}
+		return Any
+	case *ast.PostfixExpression:
+		// The value of an ok result
+		c.expression(exp.Left)
+		return Any
+	case *ast.PrefixExpression:
+		right := c.expression(exp.Right)
+		if exp.Operator == "!" {
+			return Bool
+		}
+		if right == Any {
+			// The result has the type of the operand
+			return Any
+		}
+		if exp.Operator == "-" && right == Float {
+			return Float
+		}
+		if !Consistent(right, Int) {
+			c.errorf(exp.Pos(), "unknown operator: %s%s", exp.Operator, right)
+		}
+		return Int
+	case *ast.InfixExpression:
+		return c.infix(exp)
+	case *ast.IfExpression:
+		c.expression(exp.Condition)
+		consequence := c.block(exp.Consequence)
+		if exp.Alternative == nil {
+			// The value is null when the condition is false
+			return Any
+		}
+		return join(consequence, c.block(exp.Alternative))
+	case *ast.FunctionLiteral:
+		f := c.signature(exp.Parameters, exp.ReturnType, exp)
+		c.function(f, exp.ReturnType != nil, exp.Parameters, exp.Body)
+		if optionalParameters(exp.Parameters) {
+			return Any
+		}
+		return f
+	case *ast.CallExpression:
+		return c.call(exp)
+	case *ast.SpreadExpression:
+		c.expression(exp.Value)
+		return Any
+	case *ast.KeywordArgument:
+		return c.expression(exp.Value)
+	case *ast.AssignExpression:
+		value := c.expression(exp.Expression)
+		left, ok := exp.Left.(*ast.Identifier)
+		if !ok {
+			// Arrays and hashes are not typed, their elements can be anything
+			c.expression(exp.Left)
+			return value
+		}
+		s := c.scope.find(left.Value)
+		if s == nil {
+			return value
+		}
+		t := s.vars[left.Value]
+		if decl, ok := s.inferred[left.Value]; ok {
+			// Only an annotation restricts the values of a variable
+			s.vars[left.Value] = c.record(decl, join(t, value))
+		} else if !Consistent(value, t) {
+			c.errorf(exp.Expression.Pos(), "cannot use %s as %s in assignment to %s", value, t, left.Value)
+		}
+		return value
+	case *ast.ForExpression:
+		outer := c.scope
+		c.scope = &scope{parent: outer, vars: map[string]Type{}}
+		c.statement(exp.Initial)
+		c.expression(exp.StopCondition)
+		c.expression(exp.Increment)
+		c.block(exp.Statements)
+		c.scope = outer
+		return Any
+	case *ast.WhileExpression:
+		c.expression(exp.Condition)
+		c.block(exp.Body)
+		return Any
+	case *ast.ForInExpression:
+		c.expression(exp.Iterable)
+		outer := c.scope
+		c.scope = &scope{parent: outer, vars: map[string]Type{}}
+		for _, name := range exp.Names {
+			c.scope.vars[name.Value] = c.record(name, Any)
+		}
+		c.block(exp.Body)
+		c.scope = outer
+		return Any
+	case *ast.ArrayLiteral:
+		for _, el := range exp.Elements {
+			c.expression(el)
+		}
+		return Any
+	case *ast.HashLiteral:
+		for _, pair := range exp.Pairs {
+			c.expression(pair.Key)
+			c.expression(pair.Value)
+		}
+		return Any
+	case *ast.IndexExpression:
+		c.expression(exp.Left)
+		c.expression(exp.Index)
+		return Any
+	case *ast.FieldExpression:
+		c.expression(exp.Left)
+		return Any
+	case *ast.StructLiteral:
+		c.expression(exp.Name)
+		for _, field := range exp.Fields {
+			c.expression(field.Value)
+		}
+		return Any
+	case *ast.MatchExpression:
+		c.expression(exp.Subject)
+		var t Type
+		for _, arm := range exp.Arms {
+			outer := c.scope
+			c.scope = &scope{parent: outer, vars: map[string]Type{}}
+			c.declarePattern(arm.Pattern)
+			if arm.Guard != nil {
+				c.expression(arm.Guard)
+			}
+			body := c.block(arm.Body)
+			if t == nil {
+				t = body
+			} else {
+				t = join(t, body)
+			}
+			c.scope = outer
+		}
+		if t == nil {
+			return Any
+		}
+		return t
+	case *ast.TryExpression:
+		t := c.block(exp.Body)
+		if exp.Catch != nil {
+			outer := c.scope
+			c.scope = &scope{parent: outer, vars: map[string]Type{}}
+			if exp.Param != nil {
+				c.scope.vars[exp.Param.Value] = c.record(exp.Param, Any)
+			}
+			t = join(t, c.block(exp.Catch))
+			c.scope = outer
+		}
+		c.block(exp.Finally)
+		return t
+	}
+
+	return Any
+}
+
+// declarePattern declares the variables bound by a pattern in the current
+// scope, arrays and hashes are not typed so they can be anything
+func (c *Checker) declarePattern(pattern ast.Pattern) {
+	for _, name := range ast.PatternNames(pattern) {
+		c.scope.vars[name.Value] = c.record(name, Any)
+		delete(c.scope.inferred, name.Value)
+	}
+}
+
+// block checks the statements of an if, a loop or a try, the variables they
+// declare are only visible in the block
+func (c *Checker) block(block *ast.BlockStatement) Type {
+	if block == nil {
+		return Any
+	}
+
+	outer := c.scope
+	c.scope = &scope{parent: outer, vars: map[string]Type{}}
+	t := c.statements(block.Statements)
+	c.scope = outer
+
+	return t
+}
+
+func (c *Checker) infix(exp *ast.InfixExpression) Type {
+	left := c.expression(exp.Left)
+	right := c.expression(exp.Right)
+
+	var result Type
+	arithmetic := true
+	switch exp.Operator {
+	case "==", "!=":
+		// Values of different types are never equal
+		return Bool
+	case "&&", "||":
+		// Every value is either truthy or falsy
+		return Bool
+	case "<", ">", "<=", ">=":
+		result = Bool
+	case "+", "-", "*", "/", "%":
+		result = Int
+	default:
+		// The bitwise operators only work on integers
+		arithmetic = false
+		result = Int
+	}
+
+	// The result of an arithmetic or bitwise operation has the type of its
+	// operands, it is unknown when one of them is
+	if result == Int && (left == Any || right == Any) {
+		return Any
+	}
+
+	if arithmetic && isNumber(left) && isNumber(right) {
+		// An integer mixed with a float is promoted to a float
+		if result == Int && (left == Float || right == Float) {
+			return Float
+		}
+		return result
+	}
+
+	if Consistent(left, Int) && Consistent(right, Int) {
+		return result
+	}
+
+	// An operand of unknown type could be of any type, the operation can
+	// only be reported as wrong when both types are known
+	if left != Any && right != Any {
+		if !Consistent(left, right) {
+			c.errorf(exp.Pos(), "type mismatch: %s %s %s", left, exp.Operator, right)
+		} else {
+			c.errorf(exp.Pos(), "unknown operator: %s %s %s", left, exp.Operator, right)
+		}
+	}
+
+	return result
+}
+
+func isNumber(t Type) bool {
+	return Consistent(t, Int) || t == Float
+}
+
+func (c *Checker) call(exp *ast.CallExpression) Type {
+	callee := c.expression(exp.Function)
+
+	args := []Type{}
+	for _, arg := range exp.Arguments {
+		args = append(args, c.expression(arg))
+	}
+
+	f, ok := callee.(*Function)
+	if !ok {
+		if callee != Any {
+			c.errorf(exp.Function.Pos(), "not a function: %s", callee)
+		}
+		return Any
+	}
+
+	c.checkBody(f)
+
+	if unpackedArguments(exp.Arguments) {
+		return f.Return
+	}
+
+	if len(args) != len(f.Params) {
+		c.errorf(exp.Pos(), "wrong number of arguments to %s: want=%d, got=%d", exp.Function, len(f.Params), len(args))
+		return f.Return
+	}
+
+	for i, arg := range args {
+		if !Consistent(arg, f.Params[i]) {
+			c.errorf(exp.Arguments[i].Pos(), "cannot use %s as %s in argument %d to %s", arg, f.Params[i], i+1, exp.Function)
+		}
+	}
+
+	return f.Return
+}
+
+// optionalParameters reports whether a function can be called with fewer or
+// more arguments than it has parameters. Its type doesn't say which, so its
+// calls are not checked.
+func optionalParameters(params []*ast.Declaration) bool {
+	for _, p := range params {
+		if p.Default != nil || p.Rest {
+			return true
+		}
+	}
+	return false
+}
+
+// unpackedArguments reports whether a call spreads an array or passes
+// arguments by name, its arguments can't be matched with the parameters then
+func unpackedArguments(args []ast.Expression) bool {
+	for _, arg := range args {
+		switch arg.(type) {
+		case *ast.SpreadExpression, *ast.KeywordArgument:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/rumpl/monkey-lang/ast"
+	"github.com/rumpl/monkey-lang/lexer"
+	"github.com/rumpl/monkey-lang/parser"
+)
+
+func TestCheckErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x: int = 5; x + 1;", nil},
+		{"let x = 5; x + true;", []string{"1:14: type mismatch: int + bool"}},
+		{"true + false;", []string{"1:6: unknown operator: bool + bool"}},
+		{"-true;", []string{"1:1: unknown operator: -bool"}},
+		{"1 == true;", nil},
+		{"let x: int = true;", []string{"1:14: cannot use bool as int in let statement"}},
+		{"let x: bool = 1 < 2;", nil},
+		{"let x: number = 1;", []string{"1:5: unknown type number"}},
+		{"let x = 1; x = false;", nil},
+		{"let x = 1; x = false; x + 1;", nil},
+		{"let x = 1; x = 2; x + true;", []string{"1:21: type mismatch: int + bool"}},
+		{"let x: int = 1; x = false;", []string{"1:21: cannot use bool as int in assignment to x"}},
+		{"let x = 1; if (true) { x = false; }; x + 1;", nil},
+		{"let f = fn(a) { a + true }; f(1);", nil},
+		{"fn f(x) { -x } let y: float = f(1.5);", nil},
+		{"fn f(a, b) { a + b } let y: float = f(1.5, 2.0);", nil},
+		{"let f = fn(a: int) { a + true };", []string{"1:24: type mismatch: int + bool"}},
+		{"fn add(a: int, b: int) -> int { a + b } add(1, true);", []string{"1:48: cannot use bool as int in argument 2 to add"}},
+		{"fn add(a: int, b: int) -> int { a + b } add(1);", []string{"1:44: wrong number of arguments to add: want=2, got=1"}},
+		{"fn f() -> bool { return 1; }", []string{"1:25: cannot use int as bool in return statement"}},
+		{"fn f() -> bool { 1 }", []string{"1:18: cannot use int as bool in return statement"}},
+		{"fn f() { 1 } f() + true;", []string{"1:18: type mismatch: int + bool"}},
+		{"fn f() { g() } fn g() { true } f() + 1;", []string{"1:36: type mismatch: bool + int"}},
+		{"fn f(n) { if (n < 1) { return 0; } f(n - 1) } f(1) + true;", nil},
+		{"let x = 1; x();", []string{"1:12: not a function: int"}},
+		{"let f: fn(int) -> int = fn(a: int) -> int { a }; f(true);", []string{"1:52: cannot use bool as int in argument 1 to f"}},
+		{"let f: fn(int) -> int = fn(a: bool) -> int { 1 };", []string{"1:25: cannot use fn(bool) -> int as fn(int) -> int in let statement"}},
+		{"let x = if (true) { 1 } else { false }; x + true;", nil},
+		{"let x = if (true) { 1 } else { 2 }; x + true;", []string{"1:39: type mismatch: int + bool"}},
+		{"let x: float = 1 + 2.5; let y: float = -x % 2;", nil},
+		{"let x: int = 1 * 2.5;", []string{"1:16: cannot use float as int in let statement"}},
+		{"let x: float = 1 < 2.5;", []string{"1:18: cannot use bool as float in let statement"}},
+		{"1.5 & 1;", []string{"1:5: type mismatch: float & int"}},
+		{"let x: int = int(2.5) + len([1]); let y: float = float(x);", nil},
+		{"let x = 1; if (true) { let x = true; x + 1; }; x + 1;", []string{"1:40: type mismatch: bool + int"}},
+		{"fn f(a: int, b: int = true) { a } f(1);", []string{"1:23: cannot use bool as int in default of b"}},
+		{"fn add(a: int, b: int) -> int { a + b } add(b: 1, a: 2) + add(...[1, 2]);", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, errors := Check(parse(t, tt.input))
+
+			if len(errors) != len(tt.expected) {
+				t.Fatalf("expected %d errors, got %v", len(tt.expected), errors)
+			}
+
+			for i, e := range errors {
+				if e.String() != tt.expected[i] {
+					t.Errorf("expected %q, got %q", tt.expected[i], e.String())
+				}
+			}
+		})
+	}
+}
+
+func TestInferredTypes(t *testing.T) {
+	input := `
+fn main() {
+	let a = square(3);
+	let lt = a < 10;
+	return a;
+}
+fn square(x: int) {
+	return x * x;
+}
+`
+	program := parse(t, input)
+	info, errors := Check(program)
+	if len(errors) != 0 {
+		t.Fatalf("unexpected errors %v", errors)
+	}
+
+	main := program.Statements[0].(*ast.FunctionStatement)
+	square := program.Statements[1].(*ast.FunctionStatement)
+
+	tests := []struct {
+		node     ast.Node
+		expected string
+	}{
+		{main, "fn() -> int"},
+		{square, "fn(int) -> int"},
+		{main.Body.Statements[0].(*ast.LetStatement).Name.Variable(), "int"},
+		{main.Body.Statements[1].(*ast.LetStatement).Name.Variable(), "bool"},
+		{square.Parameters[0].Variable(), "int"},
+	}
+
+	for _, tt := range tests {
+		if got := info.TypeOf(tt.node).String(); got != tt.expected {
+			t.Errorf("expected %s to be of type %s, got %s", tt.node, tt.expected, got)
+		}
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	return program
+}
@@ -0,0 +1,685 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rumpl/monkey-lang/ast"
+	"github.com/rumpl/monkey-lang/token"
+)
+
+// Null is the type of the value of an if without else and of a for loop
+var Null = &Basic{Name: "null"}
+
+// String is the type of strings
+var String = &Basic{Name: "string"}
+
+// Var is a type variable, a placeholder for a type that is not known yet
+type Var struct {
+	id int
+	// instance is the type the variable was unified with, nil while the
+	// variable is free
+	instance Type
+	// origin is the node the instance comes from
+	origin ast.Node
+}
+
+func (v *Var) String() string {
+	if v.instance != nil {
+		return v.instance.String()
+	}
+	return fmt.Sprintf("t%d", v.id)
+}
+
+// Scheme is a type where the variables in Vars can be replaced by any type,
+// it is the type of a polymorphic binding
+type Scheme struct {
+	Vars []*Var
+	Type Type
+}
+
+// String prints the type of the scheme with its variables named a, b, c...
+// in the order they appear
+func (s *Scheme) String() string {
+	return format(s.Type, map[*Var]string{})
+}
+
+func format(t Type, names map[*Var]string) string {
+	switch t := prune(t).(type) {
+	case *Var:
+		name, ok := names[t]
+		if !ok {
+			name = varName(len(names))
+			names[t] = name
+		}
+		return name
+	case *Function:
+		params := []string{}
+		for _, p := range t.Params {
+			params = append(params, format(p, names))
+		}
+		return "fn(" + strings.Join(params, ", ") + ") -> " + format(t.Return, names)
+	default:
+		return t.String()
+	}
+}
+
+func varName(i int) string {
+	name := string(rune('a' + i%26))
+	if i >= 26 {
+		name += fmt.Sprint(i / 26)
+	}
+	return name
+}
+
+// prune returns the type a variable stands for, following the chain of
+// unified variables
+func prune(t Type) Type {
+	for {
+		v, ok := t.(*Var)
+		if !ok || v.instance == nil {
+			return t
+		}
+		t = v.instance
+	}
+}
+
+// Signature is the type inferred for a binding
+type Signature struct {
+	Pos    token.Position
+	Name   string
+	Scheme *Scheme
+}
+
+func (s Signature) String() string {
+	return fmt.Sprintf("%s: %s: %s", s.Pos, s.Name, s.Scheme)
+}
+
+type environment struct {
+	parent *environment
+	vars   map[string]*Scheme
+}
+
+func (e *environment) lookup(name string) (*Scheme, bool) {
+	for ; e != nil; e = e.parent {
+		if s, ok := e.vars[name]; ok {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// Inferencer infers the most general type of the bindings of a program
+// without annotations. Bindings made by let statements holding a function
+// and by fn statements are polymorphic, a function like fn(x) { x } can be
+// used with values of any type.
+type Inferencer struct {
+	env *environment
+	// ret is the return type of the function being inferred
+	ret        Type
+	next       int
+	signatures []Signature
+	errors     []Error
+}
+
+// Infer returns the type inferred for every let and fn statement of
+// program, in the order they appear
+func Infer(program *ast.Program) ([]Signature, []Error) {
+	in := &Inferencer{}
+	return in.Infer(program)
+}
+
+// Infer returns the type inferred for every let and fn statement of
+// program, in the order they appear
+func (in *Inferencer) Infer(program *ast.Program) ([]Signature, []Error) {
+	in.env = &environment{vars: map[string]*Scheme{}}
+	in.statements(program.Statements)
+
+	return in.signatures, in.errors
+}
+
+func (in *Inferencer) fresh() *Var {
+	in.next++
+	return &Var{id: in.next}
+}
+
+func (in *Inferencer) declare(name string, s *Scheme) {
+	in.env.vars[name] = s
+}
+
+func (in *Inferencer) signature(node ast.Node, name string, s *Scheme) {
+	in.signatures = append(in.signatures, Signature{Pos: node.Pos(), Name: name, Scheme: s})
+}
+
+// instantiate returns the type of s where its variables are replaced by new
+// ones
+func (in *Inferencer) instantiate(s *Scheme) Type {
+	if len(s.Vars) == 0 {
+		return s.Type
+	}
+
+	vars := map[*Var]Type{}
+	for _, v := range s.Vars {
+		vars[v] = in.fresh()
+	}
+
+	var copy func(Type) Type
+	copy = func(t Type) Type {
+		switch t := prune(t).(type) {
+		case *Var:
+			if v, ok := vars[t]; ok {
+				return v
+			}
+			return t
+		case *Function:
+			f := &Function{Return: copy(t.Return)}
+			for _, p := range t.Params {
+				f.Params = append(f.Params, copy(p))
+			}
+			return f
+		default:
+			return t
+		}
+	}
+
+	return copy(s.Type)
+}
+
+// generalize returns the scheme of t where the variables that don't appear
+// in the environment can be replaced by any type
+func (in *Inferencer) generalize(t Type) *Scheme {
+	bound := map[*Var]bool{}
+	for e := in.env; e != nil; e = e.parent {
+		for _, s := range e.vars {
+			quantified := map[*Var]bool{}
+			for _, v := range s.Vars {
+				quantified[v] = true
+			}
+			for _, v := range freeVars(s.Type) {
+				if !quantified[v] {
+					bound[v] = true
+				}
+			}
+		}
+	}
+
+	s := &Scheme{Type: t}
+	for _, v := range freeVars(t) {
+		if !bound[v] {
+			s.Vars = append(s.Vars, v)
+		}
+	}
+
+	return s
+}
+
+func freeVars(t Type) []*Var {
+	switch t := prune(t).(type) {
+	case *Var:
+		return []*Var{t}
+	case *Function:
+		vars := []*Var{}
+		for _, p := range t.Params {
+			vars = append(vars, freeVars(p)...)
+		}
+		return append(vars, freeVars(t.Return)...)
+	}
+
+	return nil
+}
+
+func occurs(v *Var, t Type) bool {
+	for _, w := range freeVars(t) {
+		if v == w {
+			return true
+		}
+	}
+	return false
+}
+
+// origin returns the type t stands for and the node where it comes from
+func origin(t Type, node ast.Node) (Type, ast.Node) {
+	for {
+		v, ok := t.(*Var)
+		if !ok || v.instance == nil {
+			return t, node
+		}
+		t, node = v.instance, v.origin
+	}
+}
+
+// constrain unifies the type a of the node an with the type b required by
+// the node bn, both nodes are reported when the types don't unify
+func (in *Inferencer) constrain(a Type, an ast.Node, b Type, bn ast.Node) {
+	ra, rn := origin(a, an)
+	rb, rbn := origin(b, bn)
+	names := map[*Var]string{}
+	// Format the types before unify binds some of their variables
+	as, bs := format(ra, names), format(rb, names)
+
+	if err := in.unify(a, an, b, bn); err != "" {
+		in.errors = append(in.errors, Error{
+			Pos:     an.Pos(),
+			Message: fmt.Sprintf("%s: %s (%s) and %s (%s)", err, as, rn.Pos(), bs, rbn.Pos()),
+		})
+	}
+}
+
+func (in *Inferencer) unify(a Type, an ast.Node, b Type, bn ast.Node) string {
+	a, b = prune(a), prune(b)
+
+	if v, ok := a.(*Var); ok {
+		if a == b {
+			return ""
+		}
+		if occurs(v, b) {
+			return "recursive type"
+		}
+		v.instance, v.origin = b, bn
+		return ""
+	}
+
+	if _, ok := b.(*Var); ok {
+		return in.unify(b, bn, a, an)
+	}
+
+	switch a := a.(type) {
+	case *Basic:
+		if a != b {
+			return "type mismatch"
+		}
+	case *Function:
+		f, ok := b.(*Function)
+		if !ok || len(a.Params) != len(f.Params) {
+			return "type mismatch"
+		}
+		for i := range a.Params {
+			if err := in.unify(a.Params[i], an, f.Params[i], bn); err != "" {
+				return err
+			}
+		}
+		return in.unify(a.Return, an, f.Return, bn)
+	}
+
+	return ""
+}
+
+func (in *Inferencer) statements(stmts []ast.Statement) Type {
+	// Named functions can be called before their declaration, they are
+	// monomorphic until their body is inferred
+	for _, stmt := range stmts {
+		if fs, ok := stmt.(*ast.FunctionStatement); ok {
+			in.declare(fs.Name, &Scheme{Type: in.fresh()})
+		}
+	}
+
+	var result Type = Null
+	for _, stmt := range stmts {
+		result = in.statement(stmt)
+	}
+
+	return result
+}
+
+func (in *Inferencer) statement(stmt ast.Statement) Type {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		id := stmt.Name.Variable()
+		if id == nil {
+			in.expression(stmt.Value)
+			in.declarePattern(stmt.Name.Pattern)
+			return Null
+		}
+		// The variable remembers the value it was declared with, so errors
+		// point to where its type comes from
+		t := &Var{instance: in.expression(stmt.Value), origin: stmt.Value}
+		s := &Scheme{Type: t}
+		// Only functions are generalized, a variable holding another value
+		// can be assigned to and must keep a single type
+		if _, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+			s = in.generalize(t)
+		}
+		in.declare(id.Value, s)
+		in.signature(id, id.Value, s)
+		return Null
+	case *ast.ReturnStatement:
+		t := in.expression(stmt.ReturnValue)
+		if in.ret != nil {
+			in.constrain(t, stmt.ReturnValue, in.ret, stmt)
+		}
+		// A return doesn't produce a value where it is
+		return in.fresh()
+	case *ast.BreakStatement, *ast.ContinueStatement:
+		return in.fresh()
+	case *ast.ThrowStatement:
+		in.expression(stmt.Value)
+		return in.fresh()
+	case *ast.ExpressionStatement:
+		return in.expression(stmt.Expression)
+	case *ast.FunctionStatement:
+		s, _ := in.env.lookup(stmt.Name)
+		f := in.function(stmt.Parameters, stmt.Body)
+		in.constrain(s.Type, stmt, f, stmt)
+
+		// The monomorphic type the function had while its body was inferred
+		// must not keep its variables from being generalized
+		delete(in.env.vars, stmt.Name)
+		scheme := in.generalize(f)
+		in.declare(stmt.Name, scheme)
+		in.signature(stmt, stmt.Name, scheme)
+		return Null
+	case *ast.StructStatement:
+		in.declare(stmt.Name.Value, &Scheme{Type: in.fresh()})
+		return Null
+	case *ast.ImplStatement:
+		for _, m := range stmt.Methods {
+			in.function(m.Parameters, m.Body)
+		}
+		return Null
+	}
+
+	return Null
+}
+
+// function infers the type of a function. A function with optional
+// parameters gets a fresh variable, its calls are not constrained.
+func (in *Inferencer) function(params []*ast.Declaration, body *ast.BlockStatement) Type {
+	outer, outerRet := in.env, in.ret
+	in.env = &environment{parent: outer, vars: map[string]*Scheme{}}
+	in.ret = in.fresh()
+
+	f := &Function{Return: in.ret}
+	for _, p := range params {
+		v := in.fresh()
+		f.Params = append(f.Params, v)
+		if p.Default != nil {
+			in.constrain(in.expression(p.Default), p.Default, v, p)
+		}
+		id := p.Variable()
+		if id == nil {
+			in.declarePattern(p.Pattern)
+			continue
+		}
+		in.declare(id.Value, &Scheme{Type: v})
+	}
+
+	result := in.statements(body.Statements)
+	in.constrain(result, body, f.Return, body)
+
+	in.env, in.ret = outer, outerRet
+	if optionalParameters(params) {
+		return in.fresh()
+	}
+	return f
+}
+
+// declarePattern declares the variables bound by a pattern, arrays and
+// hashes are not typed so nothing is known about them
+func (in *Inferencer) declarePattern(pattern ast.Pattern) {
+	for _, name := range ast.PatternNames(pattern) {
+		in.declare(name.Value, &Scheme{Type: in.fresh()})
+	}
+}
+
+// block infers the type of the statements of an if, a loop or a try, the
+// variables they declare are only visible in the block
+func (in *Inferencer) block(block *ast.BlockStatement) Type {
+	if block == nil {
+		return Null
+	}
+
+	outer := in.env
+	in.env = &environment{parent: outer, vars: map[string]*Scheme{}}
+	t := in.statements(block.Statements)
+	in.env = outer
+
+	return t
+}
+
+func (in *Inferencer) expression(exp ast.Expression) Type {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral, *ast.BigIntegerLiteral:
+		return Int
+	case *ast.FloatLiteral:
+		return Float
+	case *ast.Boolean:
+		return Bool
+	case *ast.StringLiteral:
+		return String
+	case *ast.Identifier:
+		// Undefined variables are reported by the resolver
+		s, ok := in.env.lookup(exp.Value)
+		if !ok {
+			if f, ok := builtins()[exp.Value]; ok {
+				// The argument of a builtin can be of any type
+				return &Function{Params: []Type{in.fresh()}, Return: f.Return}
+			}
+			return in.fresh()
+		}
+		return in.instantiate(s)
+	case *ast.PostfixExpression:
+		// The value of an ok result, results are not typed
+		in.expression(exp.Left)
+		return in.fresh()
+	case *ast.PrefixExpression:
+		right := in.expression(exp.Right)
+		if exp.Operator == "!" {
+			// Every value is either truthy or falsy
+			return Bool
+		}
+		if exp.Operator == "-" && prune(right) == Float {
+			return Float
+		}
+		in.constrain(right, exp.Right, Int, exp)
+		return Int
+	case *ast.InfixExpression:
+		left := in.expression(exp.Left)
+		right := in.expression(exp.Right)
+
+		switch exp.Operator {
+		case "==", "!=":
+			in.constrain(right, exp.Right, left, exp.Left)
+			return Bool
+		case "&&", "||":
+			return Bool
+		case "<", ">", "<=", ">=":
+			in.number(exp, left, right)
+			return Bool
+		case "+":
+			// Strings are concatenated
+			if prune(left) == String || prune(right) == String {
+				in.constrain(left, exp.Left, String, exp)
+				in.constrain(right, exp.Right, String, exp)
+				return String
+			}
+			return in.number(exp, left, right)
+		case "-", "*", "/", "%":
+			return in.number(exp, left, right)
+		default:
+			in.constrain(left, exp.Left, Int, exp)
+			in.constrain(right, exp.Right, Int, exp)
+			return Int
+		}
+	case *ast.IfExpression:
+		in.expression(exp.Condition)
+		consequence := in.block(exp.Consequence)
+		if exp.Alternative == nil {
+			return Null
+		}
+		alternative := in.block(exp.Alternative)
+		in.constrain(alternative, exp.Alternative, consequence, exp.Consequence)
+		return consequence
+	case *ast.FunctionLiteral:
+		return in.function(exp.Parameters, exp.Body)
+	case *ast.CallExpression:
+		return in.call(exp)
+	case *ast.SpreadExpression:
+		in.expression(exp.Value)
+		return in.fresh()
+	case *ast.KeywordArgument:
+		return in.expression(exp.Value)
+	case *ast.AssignExpression:
+		value := in.expression(exp.Expression)
+		left, ok := exp.Left.(*ast.Identifier)
+		if !ok {
+			in.expression(exp.Left)
+			return value
+		}
+		if s, ok := in.env.lookup(left.Value); ok {
+			in.constrain(value, exp.Expression, in.instantiate(s), left)
+		}
+		return value
+	case *ast.ForExpression:
+		outer := in.env
+		in.env = &environment{parent: outer, vars: map[string]*Scheme{}}
+		in.statement(exp.Initial)
+		in.expression(exp.StopCondition)
+		in.expression(exp.Increment)
+		in.block(exp.Statements)
+		in.env = outer
+		return Null
+	case *ast.WhileExpression:
+		in.expression(exp.Condition)
+		in.block(exp.Body)
+		return Null
+	case *ast.ForInExpression:
+		in.expression(exp.Iterable)
+		outer := in.env
+		in.env = &environment{parent: outer, vars: map[string]*Scheme{}}
+		for _, name := range exp.Names {
+			in.declare(name.Value, &Scheme{Type: in.fresh()})
+		}
+		in.block(exp.Body)
+		in.env = outer
+		return Null
+	case *ast.MatchExpression:
+		subject := in.expression(exp.Subject)
+		// Arrays and hashes are not typed, a literal pattern only tells the
+		// type of the subject when no arm destructures it
+		literals := true
+		for _, arm := range exp.Arms {
+			switch arm.Pattern.(type) {
+			case *ast.ArrayPattern, *ast.HashPattern:
+				literals = false
+			}
+		}
+		var t Type = in.fresh()
+		for _, arm := range exp.Arms {
+			outer := in.env
+			in.env = &environment{parent: outer, vars: map[string]*Scheme{}}
+			switch pattern := arm.Pattern.(type) {
+			case *ast.Identifier:
+				// The whole subject is bound
+				in.declare(pattern.Value, &Scheme{Type: subject})
+			case *ast.WildcardPattern:
+			case *ast.ArrayPattern, *ast.HashPattern:
+				in.declarePattern(pattern)
+			default:
+				// A literal has the type of the subject it is compared to, but
+				// an integer and a float of the same value are equal
+				literal := in.expression(pattern)
+				number := (prune(literal) == Int || prune(literal) == Float) &&
+					(prune(subject) == Int || prune(subject) == Float)
+				if literals && !number {
+					in.constrain(literal, pattern, subject, exp.Subject)
+				}
+			}
+			if arm.Guard != nil {
+				in.expression(arm.Guard)
+			}
+			body := in.block(arm.Body)
+			in.constrain(body, arm.Body, t, exp)
+			in.env = outer
+		}
+		return t
+	case *ast.TryExpression:
+		body := in.block(exp.Body)
+		if exp.Catch != nil {
+			outer := in.env
+			in.env = &environment{parent: outer, vars: map[string]*Scheme{}}
+			if exp.Param != nil {
+				in.declare(exp.Param.Value, &Scheme{Type: in.fresh()})
+			}
+			catch := in.block(exp.Catch)
+			in.constrain(catch, exp.Catch, body, exp.Body)
+			in.env = outer
+		}
+		in.block(exp.Finally)
+		return body
+	case *ast.ArrayLiteral:
+		// The elements of an array can be of different types and indexing
+		// works on strings and hashes too, there is no type to give them
+		in.unsupported(exp, "arrays")
+		for _, el := range exp.Elements {
+			in.expression(el)
+		}
+	case *ast.HashLiteral:
+		in.unsupported(exp, "hashes")
+		for _, pair := range exp.Pairs {
+			in.expression(pair.Key)
+			in.expression(pair.Value)
+		}
+	case *ast.IndexExpression:
+		in.expression(exp.Left)
+		in.expression(exp.Index)
+	case *ast.FieldExpression:
+		in.expression(exp.Left)
+	case *ast.StructLiteral:
+		for _, field := range exp.Fields {
+			in.expression(field.Value)
+		}
+	}
+
+	return in.fresh()
+}
+
+// unsupported reports a value the inference has no type for
+func (in *Inferencer) unsupported(node ast.Node, what string) {
+	in.errors = append(in.errors, Error{Pos: node.Pos(), Message: what + " are not supported in inference"})
+}
+
+// number constrains the operands of an arithmetic operation and returns the
+// type of its result. The operands are integers unless one of them is
+// already known to be a float, an integer mixed with a float is promoted.
+func (in *Inferencer) number(exp *ast.InfixExpression, left, right Type) Type {
+	if prune(left) != Float && prune(right) != Float {
+		in.constrain(left, exp.Left, Int, exp)
+		in.constrain(right, exp.Right, Int, exp)
+		return Int
+	}
+
+	if prune(left) != Int {
+		in.constrain(left, exp.Left, Float, exp)
+	}
+	if prune(right) != Int {
+		in.constrain(right, exp.Right, Float, exp)
+	}
+	return Float
+}
+
+func (in *Inferencer) call(exp *ast.CallExpression) Type {
+	callee := in.expression(exp.Function)
+
+	args := []Type{}
+	for _, arg := range exp.Arguments {
+		args = append(args, in.expression(arg))
+	}
+
+	if unpackedArguments(exp.Arguments) {
+		return in.fresh()
+	}
+
+	// Arguments are unified one by one when the function is known, so the
+	// error points to the wrong argument
+	if f, ok := prune(callee).(*Function); ok && len(f.Params) == len(args) {
+		for i, arg := range args {
+			in.constrain(arg, exp.Arguments[i], f.Params[i], exp.Function)
+		}
+		return f.Return
+	}
+
+	f := &Function{Params: args, Return: in.fresh()}
+	in.constrain(callee, exp.Function, f, exp)
+
+	return f.Return
+}
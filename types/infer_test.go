@@ -0,0 +1,109 @@
+package types
+
+import (
+	"testing"
+)
+
+func TestInferSignatures(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 5;", []string{"1:5: x: int"}},
+		{"let f = fn(a, b) { a <= b || a % 2 == 0 };", []string{"1:5: f: fn(int, int) -> bool"}},
+		{"let f = fn(a) { 2.5 * a };", []string{"1:5: f: fn(float) -> float"}},
+		{"let x = 1 + 2.5 + int(0.5);", []string{"1:5: x: float"}},
+		{"let b = 1 < 2;", []string{"1:5: b: bool"}},
+		{"let id = fn(x) { x };", []string{"1:5: id: fn(a) -> a"}},
+		{"let add = fn(a, b) { a + b };", []string{"1:5: add: fn(int, int) -> int"}},
+		{"fn eq(a, b) { a == b }", []string{"1:1: eq: fn(a, a) -> bool"}},
+		{"let apply = fn(f, x) { f(x) };", []string{"1:5: apply: fn(fn(a) -> b, a) -> b"}},
+		{"let compose = fn(f, g) { return fn(x) { f(g(x)) } };", []string{"1:5: compose: fn(fn(a) -> b, fn(c) -> a) -> fn(c) -> b"}},
+		{"fn twice(f, x) { f(f(x)) }", []string{"1:1: twice: fn(fn(a) -> a, a) -> a"}},
+		{
+			"let id = fn(x) { x }; let a = id(1); let b = id(true);",
+			[]string{"1:5: id: fn(a) -> a", "1:27: a: int", "1:42: b: bool"},
+		},
+		{
+			"fn fact(n) { if (n < 2) { return 1; } n * fact(n - 1) }",
+			[]string{"1:1: fact: fn(int) -> int"},
+		},
+		{
+			"fn main() { even(10) } fn even(n) { if (n == 0) { true } else { odd(n - 1) } } fn odd(n) { if (n == 0) { false } else { even(n - 1) } }",
+			[]string{"1:1: main: fn() -> bool", "1:24: even: fn(int) -> bool", "1:80: odd: fn(int) -> bool"},
+		},
+		{
+			"let fib = fn(n) { match (n) { 0 => 0, 1 => 1, m => fib(m - 1) + fib(m - 2) } };",
+			[]string{"1:5: fib: fn(int) -> int"},
+		},
+		{"let x = match (2.0) { 2 => true, _ => false };", []string{"1:5: x: bool"}},
+		{`let s = "a"; let t = s + "b";`, []string{"1:5: s: string", "1:18: t: string"}},
+		{"let x = match (2) { 2.0 => 1, _ => 0 };", []string{"1:5: x: int"}},
+		{
+			"let count = fn(n) { let s = 0; for (let i = 0; i < n; i = i + 1) { s = s + i }; s };",
+			[]string{"1:25: s: int", "1:41: i: int", "1:5: count: fn(int) -> int"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			signatures, errors := Infer(parse(t, tt.input))
+			if len(errors) != 0 {
+				t.Fatalf("unexpected errors %v", errors)
+			}
+
+			if len(signatures) != len(tt.expected) {
+				t.Fatalf("expected %d signatures, got %v", len(tt.expected), signatures)
+			}
+
+			for i, s := range signatures {
+				if s.String() != tt.expected[i] {
+					t.Errorf("expected %q, got %q", tt.expected[i], s.String())
+				}
+			}
+		})
+	}
+}
+
+func TestInferErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"1 + true;", []string{"1:5: type mismatch: bool (1:5) and int (1:3)"}},
+		{
+			"let inc = fn(x) { x + 1 }; inc(true);",
+			[]string{"1:32: type mismatch: bool (1:32) and int (1:21)"},
+		},
+		{
+			"let x = 1; x = false;",
+			[]string{"1:16: type mismatch: bool (1:16) and int (1:9)"},
+		},
+		{
+			"let f = fn(x) { if (x) { 1 } else { false } };",
+			[]string{"1:35: type mismatch: bool (1:35) and int (1:24)"},
+		},
+		{"let f = fn(x) { x(x) };", []string{"1:17: recursive type: a (1:17) and fn(a) -> b (1:18)"}},
+		{"let x = 1; x(2);", []string{"1:12: type mismatch: int (1:9) and fn(int) -> a (1:13)"}},
+		{`let s = "a"; let t = s + 1;`, []string{"1:26: type mismatch: int (1:26) and string (1:24)"}},
+		{"let a = [1, 2];", []string{"1:9: arrays are not supported in inference"}},
+		{`let h = {"a": 1};`, []string{"1:9: hashes are not supported in inference"}},
+		{"match (1) { true => 1, _ => 2 };", []string{"1:13: type mismatch: bool (1:13) and int (1:8)"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, errors := Infer(parse(t, tt.input))
+
+			if len(errors) != len(tt.expected) {
+				t.Fatalf("expected %d errors, got %v", len(tt.expected), errors)
+			}
+
+			for i, e := range errors {
+				if e.String() != tt.expected[i] {
+					t.Errorf("expected %q, got %q", tt.expected[i], e.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package types
+
+import (
+	"strings"
+
+	"github.com/rumpl/monkey-lang/ast"
+)
+
+// Type is the static type of a monkey value
+type Type interface {
+	String() string
+}
+
+// Basic is a type without structure
+type Basic struct {
+	Name string
+}
+
+func (b *Basic) String() string {
+	return b.Name
+}
+
+var (
+	Int   = &Basic{Name: "int"}
+	Float = &Basic{Name: "float"}
+	Bool  = &Basic{Name: "bool"}
+	// Any is the type of the values the checker knows nothing about, it is
+	// consistent with every other type
+	Any = &Basic{Name: "any"}
+)
+
+// builtins are the types of the builtin functions whose result type is known
+func builtins() map[string]*Function {
+	return map[string]*Function{
+		"len":    {Params: []Type{Any}, Return: Int},
+		"int":    {Params: []Type{Any}, Return: Int},
+		"float":  {Params: []Type{Any}, Return: Float},
+		"is_ok":  {Params: []Type{Any}, Return: Bool},
+		"is_err": {Params: []Type{Any}, Return: Bool},
+	}
+}
+
+// Function is the type of a function
+type Function struct {
+	Params []Type
+	Return Type
+}
+
+func (f *Function) String() string {
+	params := []string{}
+	for _, p := range f.Params {
+		params = append(params, p.String())
+	}
+
+	return "fn(" + strings.Join(params, ", ") + ") -> " + f.Return.String()
+}
+
+// Consistent reports whether a value of type a can be used where b is
+// expected. Any is consistent with every type, the other types are only
+// consistent with themselves.
+func Consistent(a, b Type) bool {
+	if a == Any || b == Any {
+		return true
+	}
+
+	switch a := a.(type) {
+	case *Basic:
+		return a == b
+	case *Function:
+		f, ok := b.(*Function)
+		if !ok || len(a.Params) != len(f.Params) {
+			return false
+		}
+		for i := range a.Params {
+			if !Consistent(a.Params[i], f.Params[i]) {
+				return false
+			}
+		}
+		return Consistent(a.Return, f.Return)
+	}
+
+	return false
+}
+
+// Known reports whether t says something about the values of its type, a
+// function type is known only when its parameter and return types are
+func Known(t Type) bool {
+	switch t := t.(type) {
+	case *Basic:
+		return t != Any
+	case *Function:
+		for _, p := range t.Params {
+			if !Known(p) {
+				return false
+			}
+		}
+		return Known(t.Return)
+	}
+
+	return false
+}
+
+// join returns the type of a value that is either of type a or b
+func join(a, b Type) Type {
+	if Consistent(a, b) && Known(a) && Known(b) {
+		return a
+	}
+	return Any
+}
+
+// FromAnnotation returns the type written in an annotation, it returns Any
+// for a missing annotation
+func FromAnnotation(annotation ast.TypeExpression) (Type, bool) {
+	switch annotation := annotation.(type) {
+	case nil:
+		return Any, true
+	case *ast.NamedType:
+		switch annotation.Name {
+		case "int":
+			return Int, true
+		case "float":
+			return Float, true
+		case "bool":
+			return Bool, true
+		case "any":
+			return Any, true
+		}
+	case *ast.FunctionType:
+		f := &Function{}
+		for _, p := range annotation.Parameters {
+			t, ok := FromAnnotation(p)
+			if !ok {
+				return Any, false
+			}
+			f.Params = append(f.Params, t)
+		}
+		t, ok := FromAnnotation(annotation.Return)
+		if !ok {
+			return Any, false
+		}
+		f.Return = t
+		return f, true
+	}
+
+	return Any, false
+}